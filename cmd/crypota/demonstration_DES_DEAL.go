@@ -53,11 +53,14 @@ func testAllModesOnTextFile() {
 		{modes.CFB, "CFB"},
 		{modes.OFB, "OFB"},
 		{modes.CTR, "CTR"},
+		{modes.RandomDelta, "RandomDelta"},
 	}
 
 	fmt.Println("DES с разными режимами:")
-	desKey := make([]byte, 8)
-	rand.Read(desKey)
+	desKey, err := des.GenerateKey()
+	if err != nil {
+		log.Fatalf("Ошибка генерации ключа DES: %v", err)
+	}
 	desCipher := des.NewDESCipher()
 
 	for _, modeInfo := range allModes {
@@ -100,8 +103,10 @@ func testAllFilesWithOneMode() {
 	}
 
 	fmt.Println("DES в режиме CBC:")
-	desKey := make([]byte, 8)
-	rand.Read(desKey)
+	desKey, err := des.GenerateKey()
+	if err != nil {
+		log.Fatalf("Ошибка генерации ключа DES: %v", err)
+	}
 	desCipher := des.NewDESCipher()
 
 	for _, file := range testFiles {
@@ -0,0 +1,185 @@
+package bitops
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrNotBijective - P-блок не является перестановкой: какой-то бит повторяется или пропущен
+var ErrNotBijective = errors.New("P-block is not a bijection")
+
+// Permutation - P-блок, разобранный один раз вместе с конфигурацией. Для каждой позиции
+// входного байта и каждого его значения заранее посчитан вклад в выход, так что Apply -
+// это OR нескольких строк таблицы без выделения памяти. Результат совпадает с Permute
+type Permutation struct {
+	pBlock   []int // номера битов с нуля
+	config   PermuteConfig
+	inBytes  int
+	outBytes int
+	// table[(i*256+v)*outBytes:][:outBytes] - выходные биты, которые дает байт v на позиции i
+	table []byte
+	// used - позиции входных байтов, на которые ссылается хотя бы один выходной бит
+	used []int
+}
+
+// Compile разбирает P-блок; длина входа - наименьшее число байт, вмещающее максимальный номер бита
+func Compile(pBlock []int, config PermuteConfig) (*Permutation, error) {
+	if len(pBlock) == 0 {
+		return nil, fmt.Errorf("P-block cannot be empty")
+	}
+	if config.Indexing != LSBFirst && config.Indexing != MSBFirst {
+		return nil, fmt.Errorf("unknown bit indexing: %d", config.Indexing)
+	}
+	if config.Numbering != ZeroBased && config.Numbering != OneBased {
+		return nil, fmt.Errorf("unknown bit numbering: %d", config.Numbering)
+	}
+
+	p := &Permutation{
+		pBlock:   make([]int, len(pBlock)),
+		config:   config,
+		outBytes: (len(pBlock) + 7) / 8,
+	}
+	maxBit := 0
+	for i, src := range pBlock {
+		if config.Numbering == OneBased {
+			src--
+		}
+		if src < 0 {
+			return nil, fmt.Errorf("bit index %d at position %d is negative", src, i)
+		}
+		p.pBlock[i] = src
+		maxBit = max(maxBit, src)
+	}
+	p.inBytes = maxBit/8 + 1
+
+	p.table = make([]byte, p.inBytes*256*p.outBytes)
+	referenced := make([]bool, p.inBytes)
+	for i, src := range p.pBlock {
+		referenced[src/8] = true
+		outByte, outMask := p.outputBit(i)
+		inMask := p.inputMask(src)
+		for v := 0; v < 256; v++ {
+			if byte(v)&inMask != 0 {
+				p.table[((src/8)*256+v)*p.outBytes+outByte] |= outMask
+			}
+		}
+	}
+	for i, ok := range referenced {
+		if ok {
+			p.used = append(p.used, i)
+		}
+	}
+	return p, nil
+}
+
+// MustCompile как Compile, но паникует на ошибке; для P-блоков, заданных в коде
+func MustCompile(pBlock []int, config PermuteConfig) *Permutation {
+	p, err := Compile(pBlock, config)
+	if err != nil {
+		panic(fmt.Sprintf("bitops: %v", err))
+	}
+	return p
+}
+
+func (p *Permutation) inputMask(bit int) byte {
+	if p.config.Indexing == LSBFirst {
+		return 1 << (bit % 8)
+	}
+	return 0x80 >> (bit % 8)
+}
+
+// outputBit повторяет упаковку bitsToBytes: при MSBFirst неполный последний байт
+// заполняется начиная со своего старшего значащего бита, то есть прижат вправо
+func (p *Permutation) outputBit(i int) (int, byte) {
+	if p.config.Indexing == LSBFirst {
+		return i / 8, 1 << (i % 8)
+	}
+	width := min(8, len(p.pBlock)-i/8*8)
+	return i / 8, 1 << (width - 1 - i%8)
+}
+
+// InputBytes - сколько байт входа читает Apply
+func (p *Permutation) InputBytes() int {
+	return p.inBytes
+}
+
+// OutputBytes - сколько байт выхода пишет Apply
+func (p *Permutation) OutputBytes() int {
+	return p.outBytes
+}
+
+// Apply переставляет биты src в dst[:OutputBytes()]; src и dst не должны перекрываться
+func (p *Permutation) Apply(dst, src []byte) error {
+	if len(src) < p.inBytes {
+		return fmt.Errorf("input must be at least %d bytes, got %d", p.inBytes, len(src))
+	}
+	if len(dst) < p.outBytes {
+		return fmt.Errorf("output must be at least %d bytes, got %d", p.outBytes, len(dst))
+	}
+
+	out := dst[:p.outBytes]
+	clear(out)
+	for _, i := range p.used {
+		row := p.table[(i*256+int(src[i]))*p.outBytes:][:p.outBytes]
+		for j, b := range row {
+			out[j] |= b
+		}
+	}
+	return nil
+}
+
+// Inverse строит обратную перестановку с той же конфигурацией; P-блок должен быть биекцией.
+// При MSBFirst ширина должна быть кратна 8: вход читается от старшего бита байта,
+// а неполный выходной байт прижат вправо, и обратная перестановка не вернула бы биты на место
+func (p *Permutation) Inverse() (*Permutation, error) {
+	inverse, err := InversePBlock(p.pBlock, ZeroBased)
+	if err != nil {
+		return nil, err
+	}
+	if p.config.Indexing == MSBFirst && len(p.pBlock)%8 != 0 {
+		return nil, fmt.Errorf("inverse of %d-bit MSBFirst permutation is not supported, width must be a multiple of 8", len(p.pBlock))
+	}
+	if p.config.Numbering == OneBased {
+		for i := range inverse {
+			inverse[i]++
+		}
+	}
+	return Compile(inverse, p.config)
+}
+
+// ValidateBijective проверяет, что P-блок - перестановка битов 0..n-1 (или 1..n):
+// каждый номер встречается ровно один раз
+func ValidateBijective(pBlock []int, numbering BitNumbering) error {
+	offset := 0
+	if numbering == OneBased {
+		offset = 1
+	}
+	seen := make([]bool, len(pBlock))
+	for i, src := range pBlock {
+		bit := src - offset
+		if bit < 0 || bit >= len(pBlock) {
+			return fmt.Errorf("%w: bit %d at position %d is outside [%d, %d]", ErrNotBijective, src, i, offset, len(pBlock)-1+offset)
+		}
+		if seen[bit] {
+			return fmt.Errorf("%w: bit %d is used more than once", ErrNotBijective, src)
+		}
+		seen[bit] = true
+	}
+	return nil
+}
+
+// InversePBlock возвращает P-блок обратной перестановки в той же нумерации
+func InversePBlock(pBlock []int, numbering BitNumbering) ([]int, error) {
+	if err := ValidateBijective(pBlock, numbering); err != nil {
+		return nil, err
+	}
+	offset := 0
+	if numbering == OneBased {
+		offset = 1
+	}
+	inverse := make([]int, len(pBlock))
+	for i, src := range pBlock {
+		inverse[src-offset] = i + offset
+	}
+	return inverse, nil
+}
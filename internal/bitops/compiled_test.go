@@ -0,0 +1,184 @@
+package bitops
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func TestCompiledMatchesPermute(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	configs := []PermuteConfig{
+		{LSBFirst, ZeroBased}, {LSBFirst, OneBased},
+		{MSBFirst, ZeroBased}, {MSBFirst, OneBased},
+	}
+
+	for _, config := range configs {
+		for trial := 0; trial < 50; trial++ {
+			inBits := 8 * (1 + rng.Intn(8))
+			pBlock := make([]int, 1+rng.Intn(70))
+			for i := range pBlock {
+				pBlock[i] = rng.Intn(inBits)
+				if config.Numbering == OneBased {
+					pBlock[i]++
+				}
+			}
+			data := make([]byte, inBits/8)
+			rng.Read(data)
+
+			expected, err := Permute(data, pBlock, config)
+			if err != nil {
+				t.Fatalf("Permute failed: %v", err)
+			}
+			p, err := Compile(pBlock, config)
+			if err != nil {
+				t.Fatalf("Compile failed: %v", err)
+			}
+			dst := bytes.Repeat([]byte{0xAA}, p.OutputBytes())
+			if err := p.Apply(dst, data); err != nil {
+				t.Fatalf("Apply failed: %v", err)
+			}
+			if !bytes.Equal(dst, expected) {
+				t.Fatalf("config %+v, P-block %v: Apply %x, Permute %x", config, pBlock, dst, expected)
+			}
+		}
+	}
+}
+
+func TestApplyDoesNotAllocate(t *testing.T) {
+	pBlock := make([]int, 64)
+	for i := range pBlock {
+		pBlock[i] = 63 - i
+	}
+	p := MustCompile(pBlock, PermuteConfig{MSBFirst, ZeroBased})
+	src := make([]byte, 8)
+	dst := make([]byte, 8)
+	if allocs := testing.AllocsPerRun(100, func() { p.Apply(dst, src) }); allocs != 0 {
+		t.Errorf("Apply allocates %v times per call", allocs)
+	}
+
+	if err := p.Apply(dst, src[:7]); err == nil {
+		t.Error("Expected error for short input")
+	}
+	if err := p.Apply(dst[:7], src); err == nil {
+		t.Error("Expected error for short output")
+	}
+}
+
+func TestInversePermutation(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	for _, config := range []PermuteConfig{{LSBFirst, ZeroBased}, {MSBFirst, OneBased}} {
+		pBlock := rng.Perm(48)
+		if config.Numbering == OneBased {
+			for i := range pBlock {
+				pBlock[i]++
+			}
+		}
+		p := MustCompile(pBlock, config)
+		inverse, err := p.Inverse()
+		if err != nil {
+			t.Fatalf("Inverse failed: %v", err)
+		}
+
+		data := make([]byte, 6)
+		rng.Read(data)
+		permuted := make([]byte, 6)
+		restored := make([]byte, 6)
+		p.Apply(permuted, data)
+		inverse.Apply(restored, permuted)
+		if !bytes.Equal(restored, data) {
+			t.Errorf("config %+v: inverse does not restore input", config)
+		}
+	}
+
+	// ширина не кратна 8: при LSBFirst биты входа и выхода упакованы одинаково
+	for _, width := range []int{6, 28} {
+		p := MustCompile(rng.Perm(width), PermuteConfig{LSBFirst, ZeroBased})
+		inverse, err := p.Inverse()
+		if err != nil {
+			t.Fatalf("%d bits: Inverse failed: %v", width, err)
+		}
+		size := (width + 7) / 8
+		data := make([]byte, size)
+		rng.Read(data)
+		data[size-1] &= byte(1<<(width-8*(size-1))) - 1
+		permuted := make([]byte, size)
+		restored := make([]byte, size)
+		p.Apply(permuted, data)
+		inverse.Apply(restored, permuted)
+		if !bytes.Equal(restored, data) {
+			t.Errorf("%d bits: inverse does not restore input: %x, want %x", width, restored, data)
+		}
+	}
+
+	// а при MSBFirst неполный выходной байт прижат вправо, такую обратную не строим
+	for _, pBlock := range [][]int{{1, 0, 2, 3, 4, 5}, rng.Perm(28)} {
+		if _, err := MustCompile(pBlock, PermuteConfig{MSBFirst, ZeroBased}).Inverse(); err == nil {
+			t.Errorf("%d bits MSBFirst: expected error from Inverse", len(pBlock))
+		}
+	}
+
+	inverse, err := InversePBlock([]int{3, 1, 2}, OneBased)
+	if err != nil || !slices.Equal(inverse, []int{2, 3, 1}) {
+		t.Errorf("InversePBlock = %v, %v; want [2 3 1]", inverse, err)
+	}
+}
+
+func TestValidateBijective(t *testing.T) {
+	tests := []struct {
+		name      string
+		pBlock    []int
+		numbering BitNumbering
+		valid     bool
+	}{
+		{"identity", []int{0, 1, 2, 3}, ZeroBased, true},
+		{"one-based", []int{2, 4, 1, 3}, OneBased, true},
+		{"duplicate", []int{0, 1, 1, 3}, ZeroBased, false},
+		{"out of range", []int{0, 1, 2, 4}, ZeroBased, false},
+		{"zero in one-based", []int{0, 1, 2}, OneBased, false},
+	}
+	for _, tt := range tests {
+		err := ValidateBijective(tt.pBlock, tt.numbering)
+		if tt.valid && err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+		}
+		if !tt.valid && !errors.Is(err, ErrNotBijective) {
+			t.Errorf("%s: got %v, want ErrNotBijective", tt.name, err)
+		}
+	}
+
+	// расширение (как E в DES) компилируется, но обратной перестановки не имеет
+	p := MustCompile([]int{4, 1, 2, 3, 4, 1}, PermuteConfig{MSBFirst, OneBased})
+	if _, err := p.Inverse(); !errors.Is(err, ErrNotBijective) {
+		t.Errorf("Inverse of expansion: got %v, want ErrNotBijective", err)
+	}
+
+	if _, err := Compile(nil, PermuteConfig{}); err == nil {
+		t.Error("Expected error for empty P-block")
+	}
+	if _, err := Compile([]int{0, 1}, PermuteConfig{MSBFirst, OneBased}); err == nil {
+		t.Error("Expected error for bit 0 with one-based numbering")
+	}
+}
+
+func BenchmarkPermutation(b *testing.B) {
+	pBlock := rand.New(rand.NewSource(3)).Perm(64)
+	config := PermuteConfig{MSBFirst, ZeroBased}
+	data := make([]byte, 8)
+
+	b.Run("Permute", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			Permute(data, pBlock, config)
+		}
+	})
+
+	p := MustCompile(pBlock, config)
+	dst := make([]byte, 8)
+	b.Run("Compiled", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			p.Apply(dst, data)
+		}
+	})
+}
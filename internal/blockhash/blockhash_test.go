@@ -0,0 +1,129 @@
+package blockhash
+
+import (
+	"bytes"
+	stddes "crypto/des"
+	"encoding/hex"
+	"testing"
+
+	"github.com/Qwental/crypota/internal/deal"
+	"github.com/Qwental/crypota/internal/des"
+	"github.com/Qwental/crypota/internal/rijndael"
+)
+
+// referenceDES прогоняет уже набитое сообщение через схему на crypto/des
+func referenceDES(t *testing.T, construction Construction, padded []byte) []byte {
+	t.Helper()
+	state := make([]byte, 8)
+	for i := 0; i < len(padded); i += 8 {
+		block := padded[i : i+8]
+		key, input := state, block
+		if construction == DaviesMeyer {
+			key, input = block, state
+		}
+		c, err := stddes.NewCipher(key)
+		if err != nil {
+			t.Fatalf("des.NewCipher failed: %v", err)
+		}
+		out := make([]byte, 8)
+		c.Encrypt(out, input)
+		xor(out, input)
+		if construction == MiyaguchiPreneel {
+			xor(out, state)
+		}
+		state = out
+	}
+	return state
+}
+
+func TestSingleBlockConstructionsAgainstDES(t *testing.T) {
+	message := []byte("fingerprint me")
+	// 14 байт + 0x80 + один нуль + длина 112 бит
+	padded, _ := hex.DecodeString("66696e6765727072696e74206d6580000000000000000070")
+
+	for _, construction := range []Construction{DaviesMeyer, MatyasMeyerOseas, MiyaguchiPreneel} {
+		h, err := New(construction, des.NewDESCipher(), 8)
+		if err != nil {
+			t.Fatalf("New failed: %v", err)
+		}
+		h.Write(message)
+		expected := referenceDES(t, construction, padded)
+		if sum := h.Sum(nil); !bytes.Equal(sum, expected) {
+			t.Errorf("Construction %d: expected %x, got %x", construction, expected, sum)
+		}
+	}
+}
+
+func TestMDC2Compression(t *testing.T) {
+	// известный ответ MDC-2 для "Now is the time for all " без набивки (24 байта)
+	expected, _ := hex.DecodeString("42e50cd224baceba760bdd2bd409281a")
+	message := []byte("Now is the time for all ")
+
+	left, right := des.NewDESCipher(), des.NewDESCipher()
+	state := append(append([]byte(nil), mdc2IVA...), mdc2IVB...)
+	for i := 0; i < len(message); i += 8 {
+		var err error
+		if state, err = mdc2Compress(left, right, state, message[i:i+8]); err != nil {
+			t.Fatalf("mdc2Compress failed: %v", err)
+		}
+	}
+	if !bytes.Equal(state, expected) {
+		t.Errorf("Expected %x, got %x", expected, state)
+	}
+
+	h := NewMDC2()
+	if h.Size() != MDC2Size || h.BlockSize() != 8 {
+		t.Errorf("Unexpected MDC-2 sizes: %d, %d", h.Size(), h.BlockSize())
+	}
+}
+
+func TestHashInterfaceBehaviour(t *testing.T) {
+	dealCipher, _ := deal.NewDEALCipher(16)
+	rijndaelCipher, _ := rijndael.NewRijndaelCipher(16, 32, 0x1B)
+
+	dm, err := New(DaviesMeyer, rijndaelCipher, 32)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	mp, err := New(MiyaguchiPreneel, dealCipher, 16)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	hashes := map[string]interface {
+		Write([]byte) (int, error)
+		Sum([]byte) []byte
+		Reset()
+	}{"DM-Rijndael": dm, "MP-DEAL": mp, "MDC-2": NewMDC2()}
+
+	message := bytes.Repeat([]byte("crypota "), 9)
+	for name, h := range hashes {
+		h.Write(message)
+		oneShot := h.Sum(nil)
+		if again := h.Sum(nil); !bytes.Equal(again, oneShot) {
+			t.Errorf("%s: Sum must not change state", name)
+		}
+
+		h.Reset()
+		for i := 0; i < len(message); i += 5 {
+			end := i + 5
+			if end > len(message) {
+				end = len(message)
+			}
+			h.Write(message[i:end])
+		}
+		if incremental := h.Sum(nil); !bytes.Equal(incremental, oneShot) {
+			t.Errorf("%s: incremental hash differs from one-shot", name)
+		}
+
+		h.Reset()
+		h.Write(append(message[:len(message)-1:len(message)-1], message[len(message)-1]^0x01))
+		if changed := h.Sum(nil); bytes.Equal(changed, oneShot) {
+			t.Errorf("%s: one-bit change did not change hash", name)
+		}
+		h.Reset()
+		h.Write(message[:len(message)-8])
+		if shorter := h.Sum(nil); bytes.Equal(shorter, oneShot) {
+			t.Errorf("%s: length is not part of the hash", name)
+		}
+	}
+}
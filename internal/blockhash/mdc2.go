@@ -0,0 +1,53 @@
+package blockhash
+
+import (
+	"hash"
+
+	"github.com/Qwental/crypota/internal/des"
+	"github.com/Qwental/crypota/internal/interfaces"
+)
+
+const MDC2Size = 16
+
+// начальные значения половин MDC-2 из ISO/IEC 10118-2
+var (
+	mdc2IVA = []byte{0x52, 0x52, 0x52, 0x52, 0x52, 0x52, 0x52, 0x52}
+	mdc2IVB = []byte{0x25, 0x25, 0x25, 0x25, 0x25, 0x25, 0x25, 0x25}
+)
+
+// NewMDC2 возвращает хеш двойной длины MDC-2 на двух экземплярах DES
+func NewMDC2() hash.Hash {
+	left := des.NewDESCipher()
+	right := des.NewDESCipher()
+	iv := append(append([]byte(nil), mdc2IVA...), mdc2IVB...)
+
+	return newDigest(des.DESBlockSize, iv, func(state, block []byte) ([]byte, error) {
+		return mdc2Compress(left, right, state, block)
+	})
+}
+
+// mdc2Compress - два шага Матиаса-Мейера-Осеаса с ключами g(A), g(B) и обменом правых половин
+func mdc2Compress(left, right interfaces.BlockCipher, state, block []byte) ([]byte, error) {
+	keyA := append([]byte(nil), state[:8]...)
+	keyB := append([]byte(nil), state[8:]...)
+	keyA[0] = keyA[0]&0x9F | 0x40
+	keyB[0] = keyB[0]&0x9F | 0x20
+
+	a, err := encryptUnder(left, keyA, block)
+	if err != nil {
+		return nil, err
+	}
+	b, err := encryptUnder(right, keyB, block)
+	if err != nil {
+		return nil, err
+	}
+	xor(a, block)
+	xor(b, block)
+
+	next := make([]byte, MDC2Size)
+	copy(next[:4], a[:4])
+	copy(next[4:8], b[4:])
+	copy(next[8:12], b[:4])
+	copy(next[12:], a[4:])
+	return next, nil
+}
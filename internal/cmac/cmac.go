@@ -0,0 +1,166 @@
+package cmac
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"hash"
+
+	"github.com/Qwental/crypota/internal/gfield"
+	"github.com/Qwental/crypota/internal/interfaces"
+)
+
+// CMAC реализует CMAC/OMAC1 (NIST SP 800-38B) поверх любого шифра с блоком 8, 16 или 32 байта
+// и удовлетворяет hash.Hash, так что данные можно подавать по частям
+type CMAC struct {
+	cipher  interfaces.BlockCipher
+	k1, k2  []byte
+	state   []byte
+	buf     []byte
+	tagSize int
+}
+
+var _ hash.Hash = (*CMAC)(nil)
+
+// New создает CMAC с тегом во весь блок; ключ шифра должен быть уже установлен
+func New(cipher interfaces.BlockCipher) (*CMAC, error) {
+	return NewWithTagSize(cipher, cipher.BlockSize())
+}
+
+// NewWithTagSize создает CMAC с усеченным до tagSize байт тегом
+func NewWithTagSize(cipher interfaces.BlockCipher, tagSize int) (*CMAC, error) {
+	blockSize := cipher.BlockSize()
+	if blockSize != 8 && blockSize != 16 && blockSize != 32 {
+		return nil, fmt.Errorf("CMAC supports 8, 16 and 32 byte blocks, got %d", blockSize)
+	}
+	if tagSize <= 0 || tagSize > blockSize {
+		return nil, fmt.Errorf("CMAC tag size must be in [1, %d], got %d", blockSize, tagSize)
+	}
+
+	k1, k2, err := Subkeys(cipher)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &CMAC{
+		cipher:  cipher,
+		k1:      k1,
+		k2:      k2,
+		tagSize: tagSize,
+	}
+	m.Reset()
+	return m, nil
+}
+
+// Subkeys вырабатывает подключи K1 = dbl(E(0)) и K2 = dbl(K1)
+func Subkeys(cipher interfaces.BlockCipher) (k1, k2 []byte, err error) {
+	l, err := cipher.EncryptBlock(make([]byte, cipher.BlockSize()))
+	if err != nil {
+		return nil, nil, fmt.Errorf("subkey generation failed: %w", err)
+	}
+	if k1, err = gfield.Double(l); err != nil {
+		return nil, nil, err
+	}
+	if k2, err = gfield.Double(k1); err != nil {
+		return nil, nil, err
+	}
+	return k1, k2, nil
+}
+
+// Write обрабатывает все полные блоки, кроме последнего: его маскировка подключом
+// зависит от того, будет ли он последним в сообщении
+func (m *CMAC) Write(p []byte) (int, error) {
+	blockSize := m.cipher.BlockSize()
+	m.buf = append(m.buf, p...)
+	for len(m.buf) > blockSize {
+		if err := m.absorb(m.state, m.buf[:blockSize]); err != nil {
+			return 0, err
+		}
+		m.buf = m.buf[blockSize:]
+	}
+	return len(p), nil
+}
+
+func (m *CMAC) absorb(state, block []byte) error {
+	for i := range state {
+		state[i] ^= block[i]
+	}
+	encrypted, err := m.cipher.EncryptBlock(state)
+	if err != nil {
+		return fmt.Errorf("CMAC block encryption failed: %w", err)
+	}
+	copy(state, encrypted)
+	return nil
+}
+
+// Sum дописывает тег к b, не меняя текущего состояния
+func (m *CMAC) Sum(b []byte) []byte {
+	tag, err := m.tag()
+	if err != nil {
+		// шифр уже проверен в New, ошибка здесь означает, что его ключ испортили снаружи
+		panic(err)
+	}
+	return append(b, tag...)
+}
+
+func (m *CMAC) tag() ([]byte, error) {
+	blockSize := m.cipher.BlockSize()
+	state := make([]byte, blockSize)
+	copy(state, m.state)
+
+	last := make([]byte, blockSize)
+	copy(last, m.buf)
+	subkey := m.k1
+	if len(m.buf) < blockSize {
+		last[len(m.buf)] = 0x80
+		subkey = m.k2
+	}
+	for i := range last {
+		last[i] ^= subkey[i]
+	}
+
+	if err := m.absorb(state, last); err != nil {
+		return nil, err
+	}
+	return state[:m.tagSize], nil
+}
+
+func (m *CMAC) Reset() {
+	m.state = make([]byte, m.cipher.BlockSize())
+	m.buf = m.buf[:0]
+}
+
+func (m *CMAC) Size() int {
+	return m.tagSize
+}
+
+func (m *CMAC) BlockSize() int {
+	return m.cipher.BlockSize()
+}
+
+// MAC считает полный CMAC от data за один вызов
+func MAC(cipher interfaces.BlockCipher, data []byte) ([]byte, error) {
+	m, err := New(cipher)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := m.Write(data); err != nil {
+		return nil, err
+	}
+	return m.tag()
+}
+
+// Verify сравнивает тег (возможно усеченный) с CMAC от data за постоянное время
+func Verify(cipher interfaces.BlockCipher, data, tag []byte) (bool, error) {
+	m, err := NewWithTagSize(cipher, len(tag))
+	if err != nil {
+		return false, err
+	}
+	if _, err := m.Write(data); err != nil {
+		return false, err
+	}
+	expected, err := m.tag()
+	if err != nil {
+		return false, err
+	}
+	return subtle.ConstantTimeCompare(expected, tag) == 1, nil
+}
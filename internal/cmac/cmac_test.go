@@ -0,0 +1,139 @@
+package cmac
+
+import (
+	"bytes"
+	"crypto/aes"
+	stddes "crypto/des"
+	"encoding/hex"
+	"testing"
+
+	"github.com/Qwental/crypota/internal/deal"
+	"github.com/Qwental/crypota/internal/des"
+	"github.com/Qwental/crypota/internal/interfaces"
+	"github.com/Qwental/crypota/internal/stdblock"
+)
+
+const nistMessage = "6bc1bee22e409f96e93d7e117393172aae2d8a571e03ac9c9eb76fac45af8e5130c81c46a35ce411e5fbc1191a0a52eff69f2445df4f9b17ad2b417be66c3710"
+
+func TestCMACKnownAnswer(t *testing.T) {
+	aesKey, _ := hex.DecodeString("2b7e151628aed2a6abf7158809cf4f3c")
+	aesCipher, err := stdblock.NewBlockCipherFunc(aes.NewCipher, aesKey)
+	if err != nil {
+		t.Fatalf("aes.NewCipher failed: %v", err)
+	}
+	tdeaKey, _ := hex.DecodeString("8aa83bf8cbda10620bc1bf19fbb6cd58bc313d4a371ca8b5")
+	tdeaCipher, err := stdblock.NewBlockCipherFunc(stddes.NewTripleDESCipher, tdeaKey)
+	if err != nil {
+		t.Fatalf("des.NewTripleDESCipher failed: %v", err)
+	}
+	message, _ := hex.DecodeString(nistMessage)
+
+	tests := []struct {
+		name   string
+		cipher *stdblock.BlockCipher
+		length int
+		tag    string
+	}{
+		{"AES-128 empty", aesCipher, 0, "bb1d6929e95937287fa37d129b756746"},
+		{"AES-128 16 bytes", aesCipher, 16, "070a16b46b4d4144f79bdd9dd04a287c"},
+		{"AES-128 40 bytes", aesCipher, 40, "dfa66747de9ae63030ca32611497c827"},
+		{"AES-128 64 bytes", aesCipher, 64, "51f0bebf7e3b9d92fc49741779363cfe"},
+		{"TDEA empty", tdeaCipher, 0, "b7a688e122ffaf95"},
+		{"TDEA 8 bytes", tdeaCipher, 8, "8e8f293136283797"},
+		{"TDEA 20 bytes", tdeaCipher, 20, "743ddbe0ce2dc2ed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expected, _ := hex.DecodeString(tt.tag)
+			tag, err := MAC(tt.cipher, message[:tt.length])
+			if err != nil {
+				t.Fatalf("MAC failed: %v", err)
+			}
+			if !bytes.Equal(tag, expected) {
+				t.Errorf("Mismatch\nExpected: %x\nGot:      %x", expected, tag)
+			}
+		})
+	}
+}
+
+func testCiphers(t *testing.T) map[string]interfaces.BlockCipher {
+	t.Helper()
+	dealCipher, err := deal.NewDEALCipher(16)
+	if err != nil {
+		t.Fatalf("NewDEALCipher failed: %v", err)
+	}
+	if err := dealCipher.SetKey([]byte("0123456789abcdef")); err != nil {
+		t.Fatalf("DEAL SetKey failed: %v", err)
+	}
+	desCipher := des.NewDESCipher()
+	if err := desCipher.SetKey([]byte{0x13, 0x34, 0x57, 0x79, 0x9B, 0xBC, 0xDF, 0xF1}); err != nil {
+		t.Fatalf("DES SetKey failed: %v", err)
+	}
+	return map[string]interfaces.BlockCipher{"DEAL": dealCipher, "DES": desCipher}
+}
+
+func TestCMACIncremental(t *testing.T) {
+	message, _ := hex.DecodeString(nistMessage)
+
+	for name, cipher := range testCiphers(t) {
+		t.Run(name, func(t *testing.T) {
+			mac, err := New(cipher)
+			if err != nil {
+				t.Fatalf("New failed: %v", err)
+			}
+
+			for _, length := range []int{0, 1, 8, 16, 17, 64} {
+				oneShot, err := MAC(cipher, message[:length])
+				if err != nil {
+					t.Fatalf("MAC failed: %v", err)
+				}
+
+				mac.Reset()
+				for i := 0; i < length; i += 3 {
+					end := i + 3
+					if end > length {
+						end = length
+					}
+					if _, err := mac.Write(message[i:end]); err != nil {
+						t.Fatalf("Write failed: %v", err)
+					}
+				}
+				if incremental := mac.Sum(nil); !bytes.Equal(incremental, oneShot) {
+					t.Errorf("Length %d: incremental tag %x differs from one-shot %x", length, incremental, oneShot)
+				}
+				if len(oneShot) != cipher.BlockSize() {
+					t.Errorf("Tag length %d, want %d", len(oneShot), cipher.BlockSize())
+				}
+			}
+		})
+	}
+}
+
+func TestCMACTruncatedVerify(t *testing.T) {
+	cipher := testCiphers(t)["DEAL"]
+	message := []byte("file produced by CipherContext")
+
+	mac, err := NewWithTagSize(cipher, 8)
+	if err != nil {
+		t.Fatalf("NewWithTagSize failed: %v", err)
+	}
+	mac.Write(message)
+	tag := mac.Sum(nil)
+	if len(tag) != 8 || mac.Size() != 8 {
+		t.Fatalf("Expected 8-byte tag, got %d", len(tag))
+	}
+
+	ok, err := Verify(cipher, message, tag)
+	if err != nil || !ok {
+		t.Errorf("Verify rejected a valid tag: %v", err)
+	}
+	tag[0] ^= 0x01
+	if ok, _ := Verify(cipher, message, tag); ok {
+		t.Errorf("Verify accepted a tampered tag")
+	}
+
+	if _, err := NewWithTagSize(cipher, 17); err == nil {
+		t.Error("Expected error for tag longer than block")
+	}
+}
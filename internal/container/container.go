@@ -0,0 +1,109 @@
+package container
+
+import (
+	"bufio"
+	stdcontext "context"
+	"fmt"
+	"os"
+
+	"github.com/Qwental/crypota/internal/context"
+	"github.com/Qwental/crypota/internal/deal"
+	"github.com/Qwental/crypota/internal/des"
+	"github.com/Qwental/crypota/internal/interfaces"
+	"github.com/Qwental/crypota/internal/modes"
+	"github.com/Qwental/crypota/internal/rijndael"
+)
+
+// NewCipher создает шифр по идентификатору из заголовка, ключ не устанавливается
+func NewCipher(id interfaces.CipherID, param byte, keySize, blockSize int) (interfaces.BlockCipher, error) {
+	var cipher interfaces.BlockCipher
+	var err error
+	switch id {
+	case interfaces.CipherDES:
+		cipher = des.NewFastDESCipher()
+	case interfaces.CipherTripleDES:
+		cipher, err = des.NewTripleDESCipher(keySize)
+	case interfaces.CipherDESX:
+		cipher = des.NewDESXCipher()
+	case interfaces.CipherDEAL:
+		cipher, err = deal.NewDEALCipher(keySize)
+	case interfaces.CipherRijndael:
+		cipher, err = rijndael.NewRijndaelCipher(blockSize, keySize, param)
+	default:
+		return nil, fmt.Errorf("cannot reconstruct cipher with id %d", id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if cipher.BlockSize() != blockSize {
+		return nil, fmt.Errorf("cipher block size %d does not match container block size %d", cipher.BlockSize(), blockSize)
+	}
+	return cipher, nil
+}
+
+// NewCipherContext собирает контекст по заголовку файла и ключу;
+// params дополняют заголовок тем, чего в нем нет (ассоциированные данные)
+func NewCipherContext(h *context.FileHeader, key []byte, params ...interface{}) (*context.CipherContext, error) {
+	if len(key) != h.KeySize {
+		return nil, fmt.Errorf("key size %d does not match container key size %d", len(key), h.KeySize)
+	}
+
+	// в SIV ключ делится пополам между двумя экземплярами шифра
+	cipherKeySize := h.KeySize
+	if h.Mode == modes.SIV {
+		cipherKeySize /= 2
+	}
+	cipher, err := NewCipher(h.Cipher, h.CipherParam, cipherKeySize, h.BlockSize)
+	if err != nil {
+		return nil, err
+	}
+
+	if h.SegmentBits != 0 {
+		params = append(params, modes.SegmentSize(h.SegmentBits))
+	}
+	switch h.Mode {
+	case modes.CCM, modes.EAX, modes.OCB:
+		params = append(params, modes.TagSize(h.TagSize))
+	case modes.SIV:
+		macCipher, err := NewCipher(h.Cipher, h.CipherParam, cipherKeySize, h.BlockSize)
+		if err != nil {
+			return nil, err
+		}
+		params = append(params, macCipher)
+	}
+
+	return context.NewCipherContext(cipher, key, h.Mode, h.Padding, h.IV, params...)
+}
+
+// ReadHeader читает заголовок контейнера из файла
+func ReadHeader(path string) (*context.FileHeader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read input file: %w", err)
+	}
+	defer file.Close()
+	return context.ReadFileHeader(bufio.NewReader(file))
+}
+
+// DecryptFile расшифровывает контейнер, зная только ключ: алгоритм, режим, набивка и IV берутся из заголовка
+func DecryptFile(inputPath, outputPath string, key []byte, params ...interface{}) error {
+	return DecryptFileContext(stdcontext.Background(), inputPath, outputPath, key, nil, params...)
+}
+
+func DecryptFileContext(
+	c stdcontext.Context,
+	inputPath, outputPath string,
+	key []byte,
+	progress context.ProgressFunc,
+	params ...interface{},
+) error {
+	h, err := ReadHeader(inputPath)
+	if err != nil {
+		return err
+	}
+	ctx, err := NewCipherContext(h, key, params...)
+	if err != nil {
+		return err
+	}
+	return ctx.DecryptFileContext(c, inputPath, outputPath, progress)
+}
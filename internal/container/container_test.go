@@ -0,0 +1,104 @@
+package container
+
+import (
+	"bytes"
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Qwental/crypota/internal/context"
+	"github.com/Qwental/crypota/internal/deal"
+	"github.com/Qwental/crypota/internal/des"
+	"github.com/Qwental/crypota/internal/interfaces"
+	"github.com/Qwental/crypota/internal/modes"
+	"github.com/Qwental/crypota/internal/padding"
+	"github.com/Qwental/crypota/internal/rijndael"
+)
+
+func TestDecryptFileWithKeyOnly(t *testing.T) {
+	newRijndael := func(blockSize, keySize int) func() interfaces.BlockCipher {
+		return func() interfaces.BlockCipher {
+			c, _ := rijndael.NewRijndaelCipher(blockSize, keySize, 0x1B)
+			return c
+		}
+	}
+	newTripleDES := func() interfaces.BlockCipher {
+		c, _ := des.NewTripleDESCipher(16)
+		return c
+	}
+	newDEAL := func() interfaces.BlockCipher {
+		c, _ := deal.NewDEALCipher(24)
+		return c
+	}
+
+	tests := []struct {
+		name    string
+		cipher  func() interfaces.BlockCipher
+		keySize int
+		mode    modes.CipherMode
+		ivSize  int
+		params  []interface{}
+	}{
+		{"DES-CBC", des.NewDESCipher, 8, modes.CBC, 8, nil},
+		{"3DES-EDE2-CBC", newTripleDES, 16, modes.CBC, 8, nil},
+		{"DESX-CTR", des.NewDESXCipher, 24, modes.CTR, 8, nil},
+		{"DEAL-CFB8", newDEAL, 24, modes.CFB, 16, []interface{}{modes.SegmentSize(8)}},
+		{"Rijndael256-CTR", newRijndael(32, 16), 16, modes.CTR, 32, nil},
+		{"Rijndael-CCM", newRijndael(16, 16), 16, modes.CCM, 12, []interface{}{modes.TagSize(8)}},
+		{"Rijndael-GCM", newRijndael(16, 32), 32, modes.GCM, 12, nil},
+		{"Rijndael-SIV", newRijndael(16, 16), 32, modes.SIV, 0, []interface{}{newRijndael(16, 16)()}},
+	}
+
+	dir := t.TempDir()
+	plaintext := make([]byte, 1000)
+	rand.Read(plaintext)
+	inputPath := filepath.Join(dir, "input.bin")
+	if err := os.WriteFile(inputPath, plaintext, 0644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key := make([]byte, tt.keySize)
+			rand.Read(key)
+			var iv []byte
+			if tt.ivSize > 0 {
+				iv = make([]byte, tt.ivSize)
+				rand.Read(iv)
+			}
+
+			ctx, err := context.NewCipherContext(tt.cipher(), key, tt.mode, padding.PKCS7, iv, tt.params...)
+			if err != nil {
+				t.Fatalf("NewCipherContext failed: %v", err)
+			}
+			encryptedPath := filepath.Join(dir, tt.name+".enc")
+			decryptedPath := filepath.Join(dir, tt.name+".dec")
+			if err := ctx.EncryptFile(inputPath, encryptedPath); err != nil {
+				t.Fatalf("EncryptFile failed: %v", err)
+			}
+
+			if err := DecryptFile(encryptedPath, decryptedPath, key); err != nil {
+				t.Fatalf("DecryptFile failed: %v", err)
+			}
+			decrypted, _ := os.ReadFile(decryptedPath)
+			if !bytes.Equal(decrypted, plaintext) {
+				t.Errorf("Round-trip failed")
+			}
+
+			wrongKey := make([]byte, tt.keySize+1)
+			if err := DecryptFile(encryptedPath, decryptedPath, wrongKey); err == nil {
+				t.Error("Expected error for key of wrong size")
+			}
+		})
+	}
+}
+
+func TestNewCipherRejectsUnknownID(t *testing.T) {
+	if _, err := NewCipher(interfaces.CipherUnknown, 0, 16, 16); err == nil {
+		t.Error("Expected error for unknown cipher id")
+	}
+	if _, err := NewCipher(interfaces.CipherDES, 0, 8, 16); err == nil {
+		t.Error("Expected error for block size mismatch")
+	}
+}
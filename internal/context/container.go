@@ -0,0 +1,215 @@
+package context
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/Qwental/crypota/internal/interfaces"
+	"github.com/Qwental/crypota/internal/modes"
+	"github.com/Qwental/crypota/internal/padding"
+)
+
+// Формат файла, который пишет EncryptFile (все числа big-endian):
+//
+//	magic "CRPT" | version | cipher id | cipher param | key size (2) | block size |
+//	mode | padding | segment bits (2) | tag size | IV length | IV | шифртекст
+//
+// Тег AEAD-режимов, как и в Encrypt, идет в конце шифртекста, в заголовке записана его длина.
+// Собрать контекст только по заголовку и ключу умеет пакет container
+const (
+	ContainerVersion  = 1
+	containerFixedLen = 16
+)
+
+var containerMagic = []byte("CRPT")
+
+// ErrNotContainer - файл не начинается с заголовка контейнера
+var ErrNotContainer = errors.New("not an encrypted file container")
+
+// FileFormat определяет, что DecryptFile делает с файлом без заголовка, передается в params контекста
+type FileFormat int
+
+const (
+	// ContainerOnly - файл без заголовка контейнера отвергается с ErrNotContainer
+	ContainerOnly FileFormat = iota
+	// AllowRawFiles - файл без заголовка расшифровывается как сырой шифртекст
+	// с IV и параметрами самого контекста
+	AllowRawFiles
+)
+
+func fileFormat(params []interface{}) FileFormat {
+	for _, p := range params {
+		if format, ok := p.(FileFormat); ok {
+			return format
+		}
+	}
+	return ContainerOnly
+}
+
+// FileHeader - все, что нужно, кроме ключа, чтобы собрать CipherContext для расшифрования файла
+type FileHeader struct {
+	Version     byte
+	Cipher      interfaces.CipherID
+	CipherParam byte // для Rijndael - многочлен поля GF(2^8)
+	KeySize     int
+	BlockSize   int
+	Mode        modes.CipherMode
+	Padding     padding.PaddingMode
+	SegmentBits int
+	TagSize     int
+	IV          []byte
+}
+
+func identifyCipher(cipher interfaces.BlockCipher) (interfaces.CipherID, byte) {
+	if identified, ok := cipher.(interfaces.IdentifiedCipher); ok {
+		return identified.Identify()
+	}
+	return interfaces.CipherUnknown, 0
+}
+
+// header описывает контекст заголовком контейнера
+func (ctx *CipherContext) header() *FileHeader {
+	id, param := identifyCipher(ctx.cipher)
+	h := &FileHeader{
+		Version:     ContainerVersion,
+		Cipher:      id,
+		CipherParam: param,
+		KeySize:     ctx.keySize,
+		BlockSize:   ctx.cipher.BlockSize(),
+		Mode:        ctx.cipherMode,
+		Padding:     ctx.paddingMode,
+		SegmentBits: segmentSize(ctx.params),
+		IV:          ctx.iv,
+	}
+	if aead, ok := ctx.mode.(modes.AEADMode); ok {
+		h.TagSize = aead.Overhead()
+	}
+	return h
+}
+
+func (h *FileHeader) MarshalBinary() ([]byte, error) {
+	if len(h.IV) > 0xFF {
+		return nil, fmt.Errorf("IV of %d bytes does not fit into container header", len(h.IV))
+	}
+	if h.KeySize > 0xFFFF || h.BlockSize > 0xFF || h.SegmentBits > 0xFFFF || h.TagSize > 0xFF {
+		return nil, fmt.Errorf("header field out of range")
+	}
+
+	buf := make([]byte, 0, containerFixedLen+len(h.IV))
+	buf = append(buf, containerMagic...)
+	buf = append(buf, h.Version, byte(h.Cipher), h.CipherParam)
+	buf = binary.BigEndian.AppendUint16(buf, uint16(h.KeySize))
+	buf = append(buf, byte(h.BlockSize), byte(h.Mode), byte(h.Padding))
+	buf = binary.BigEndian.AppendUint16(buf, uint16(h.SegmentBits))
+	buf = append(buf, byte(h.TagSize), byte(len(h.IV)))
+	buf = append(buf, h.IV...)
+	return buf, nil
+}
+
+// ReadFileHeader читает заголовок контейнера; ErrNotContainer, если магия не совпала
+func ReadFileHeader(r io.Reader) (*FileHeader, error) {
+	fixed := make([]byte, containerFixedLen)
+	if _, err := io.ReadFull(r, fixed); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, ErrNotContainer
+		}
+		return nil, fmt.Errorf("failed to read container header: %w", err)
+	}
+	if !bytes.Equal(fixed[:4], containerMagic) {
+		return nil, ErrNotContainer
+	}
+
+	h := &FileHeader{
+		Version:     fixed[4],
+		Cipher:      interfaces.CipherID(fixed[5]),
+		CipherParam: fixed[6],
+		KeySize:     int(binary.BigEndian.Uint16(fixed[7:9])),
+		BlockSize:   int(fixed[9]),
+		Mode:        modes.CipherMode(fixed[10]),
+		Padding:     padding.PaddingMode(fixed[11]),
+		SegmentBits: int(binary.BigEndian.Uint16(fixed[12:14])),
+		TagSize:     int(fixed[14]),
+	}
+	if h.Version != ContainerVersion {
+		return nil, fmt.Errorf("unsupported container version %d", h.Version)
+	}
+
+	h.IV = make([]byte, fixed[15])
+	if _, err := io.ReadFull(r, h.IV); err != nil {
+		return nil, fmt.Errorf("failed to read container IV: %w", err)
+	}
+	if len(h.IV) == 0 {
+		h.IV = nil
+	}
+	return h, nil
+}
+
+// encryptContainer пишет заголовок и шифртекст; при PerMessageIV свежий IV попадает
+// в заголовок, отдельного префикса перед шифртекстом нет
+func (ctx *CipherContext) encryptContainer(dst io.Writer, src io.Reader) error {
+	msg, _, err := ctx.beginMessage()
+	if err != nil {
+		return err
+	}
+	header, err := msg.header().MarshalBinary()
+	if err != nil {
+		return err
+	}
+	if _, err := dst.Write(header); err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+	return msg.encryptStream(dst, src)
+}
+
+// decryptContainer расшифровывает контейнер этим контекстом: заголовок должен описывать
+// тот же алгоритм и режим, а IV берется из файла. Файлы без заголовка расшифровываются
+// как есть только с AllowRawFiles
+func (ctx *CipherContext) decryptContainer(dst io.Writer, src io.Reader) error {
+	reader := bufio.NewReader(src)
+	magic, err := reader.Peek(len(containerMagic))
+	// io.EOF - файл короче магии, это тоже "нет заголовка"; прочие ошибки чтения отдаем наверх
+	if err != nil && err != io.EOF {
+		return fmt.Errorf("failed to read input: %w", err)
+	}
+	if err != nil || !bytes.Equal(magic, containerMagic) {
+		if fileFormat(ctx.params) != AllowRawFiles {
+			return ErrNotContainer
+		}
+		return ctx.DecryptStream(dst, reader)
+	}
+
+	h, err := ReadFileHeader(reader)
+	if err != nil {
+		return err
+	}
+	if err := ctx.checkHeader(h); err != nil {
+		return err
+	}
+
+	fileCtx := ctx
+	if !bytes.Equal(h.IV, ctx.iv) {
+		if fileCtx, err = ctx.withIV(h.IV); err != nil {
+			return err
+		}
+	}
+	return fileCtx.decryptStream(dst, reader)
+}
+
+func (ctx *CipherContext) checkHeader(h *FileHeader) error {
+	own := ctx.header()
+	switch {
+	case h.Cipher != own.Cipher || h.CipherParam != own.CipherParam:
+		return fmt.Errorf("file was encrypted with cipher %d, context uses %d", h.Cipher, own.Cipher)
+	case h.KeySize != own.KeySize || h.BlockSize != own.BlockSize:
+		return fmt.Errorf("file key/block size %d/%d does not match context %d/%d", h.KeySize, h.BlockSize, own.KeySize, own.BlockSize)
+	case h.Mode != own.Mode || h.Padding != own.Padding:
+		return fmt.Errorf("file mode/padding %d/%d does not match context %d/%d", h.Mode, h.Padding, own.Mode, own.Padding)
+	case h.SegmentBits != own.SegmentBits || h.TagSize != own.TagSize:
+		return fmt.Errorf("file segment/tag size does not match context")
+	}
+	return nil
+}
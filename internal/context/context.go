@@ -1,8 +1,13 @@
 package context
 
 import (
+	"bufio"
+	"bytes"
+	stdcontext "context"
 	"fmt"
+	"io"
 	"os"
+	"sync/atomic"
 
 	"github.com/Qwental/crypota/internal/interfaces"
 	"github.com/Qwental/crypota/internal/modes"
@@ -19,17 +24,84 @@ type CipherContext struct {
 	mode        modes.Mode
 	paddingMode padding.PaddingMode
 	cipherMode  modes.CipherMode
+
+	// параметры, из которых собран режим, - нужны для заголовка файла и пересборки режима с другим IV
+	iv        []byte
+	keySize   int
+	macCipher interfaces.BlockCipher
+	params    []interface{}
+
+	ivPolicy IVPolicy
+	ivUsed   atomic.Bool
 }
 
+// isStreamMode сообщает, что режим сам справляется с произвольной длиной и набивка ему не нужна
 func isStreamMode(m modes.CipherMode) bool {
 	switch m {
-	case modes.CFB, modes.OFB, modes.CTR:
+	case modes.CFB, modes.OFB, modes.CTR, modes.GCM, modes.CCM, modes.EAX, modes.OCB, modes.SIV,
+		modes.CBCCS1, modes.CBCCS2, modes.CBCCS3:
 		return true
 	default:
 		return false
 	}
 }
 
+// isStealingMode - CBC с кражей шифртекста (CS1, CS2, CS3)
+func isStealingMode(m modes.CipherMode) bool {
+	return m == modes.CBCCS1 || m == modes.CBCCS2 || m == modes.CBCCS3
+}
+
+// associatedData достает из params ассоциированные данные ([]byte) для режимов с аутентификацией
+func associatedData(params []interface{}) []byte {
+	for _, p := range params {
+		if data, ok := p.([]byte); ok {
+			return data
+		}
+	}
+	return nil
+}
+
+// associatedDataVector собирает все []byte из params в вектор ассоциированных данных (SIV)
+func associatedDataVector(params []interface{}) [][]byte {
+	var vector [][]byte
+	for _, p := range params {
+		if data, ok := p.([]byte); ok {
+			vector = append(vector, data)
+		}
+	}
+	return vector
+}
+
+// macCipher достает из params отдельный экземпляр шифра для S2V в режиме SIV
+func macCipher(params []interface{}) interfaces.BlockCipher {
+	for _, p := range params {
+		if c, ok := p.(interfaces.BlockCipher); ok {
+			return c
+		}
+	}
+	return nil
+}
+
+// tagSize достает из params длину тега (modes.TagSize), иначе возвращает defaultSize
+func tagSize(params []interface{}, defaultSize int) int {
+	for _, p := range params {
+		if size, ok := p.(modes.TagSize); ok {
+			return int(size)
+		}
+	}
+	return defaultSize
+}
+
+// segmentSize достает размер сегмента CFB/OFB в битах, 0 - полный блок
+func segmentSize(params []interface{}) int {
+	for _, p := range params {
+		if size, ok := p.(modes.SegmentSize); ok {
+			return int(size)
+		}
+	}
+	return 0
+}
+
 func NewCipherContext(
 	cipher interfaces.BlockCipher,
 	key []byte,
@@ -39,10 +111,91 @@ func NewCipherContext(
 	params ...interface{},
 ) (*CipherContext, error) {
 
+	keySize := len(key)
+
+	// в SIV ключ двойной длины: первая половина для S2V, вторая для CTR
+	var sivMACCipher interfaces.BlockCipher
+	if cipherMode == modes.SIV {
+		if sivMACCipher = macCipher(params); sivMACCipher == nil {
+			return nil, fmt.Errorf("SIV mode requires a second cipher instance in params")
+		}
+		if len(key)%2 != 0 {
+			return nil, fmt.Errorf("SIV key must consist of two equal halves, got %d bytes", len(key))
+		}
+		if err := sivMACCipher.SetKey(key[:len(key)/2]); err != nil {
+			return nil, fmt.Errorf("failed to set SIV MAC key: %w", err)
+		}
+		key = key[len(key)/2:]
+	}
+
 	if err := cipher.SetKey(key); err != nil {
 		return nil, fmt.Errorf("failed to set key: %w", err)
 	}
 
+	// кража шифртекста сохраняет длину сообщения, набивку молча отбрасывать нельзя
+	if isStealingMode(cipherMode) && paddingMode != padding.None {
+		return nil, fmt.Errorf("mode does not use padding, pass padding.None")
+	}
+
+	policy := ivPolicy(params)
+	if policy == PerMessageIV {
+		if iv != nil {
+			return nil, fmt.Errorf("IV must be nil when a fresh IV is generated per message")
+		}
+		size := generatedIVSize(cipherMode, cipher.BlockSize())
+		if size == 0 {
+			return nil, fmt.Errorf("mode %d does not use IV", cipherMode)
+		}
+		// IV для проверки параметров режима, каждое сообщение все равно получит свой
+		var err error
+		if iv, err = modes.GenerateIV(size); err != nil {
+			return nil, fmt.Errorf("IV generation failed: %w", err)
+		}
+	}
+
+	mode, err := buildMode(cipher, cipherMode, iv, sivMACCipher, params)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CipherContext{
+		cipher:      cipher,
+		mode:        mode,
+		paddingMode: paddingMode,
+		cipherMode:  cipherMode,
+		iv:          iv,
+		keySize:     keySize,
+		macCipher:   sivMACCipher,
+		params:      params,
+		ivPolicy:    policy,
+	}, nil
+}
+
+// withIV возвращает контекст для одного сообщения, режим которого собран заново с другим IV (nonce)
+func (ctx *CipherContext) withIV(iv []byte) (*CipherContext, error) {
+	mode, err := buildMode(ctx.cipher, ctx.cipherMode, iv, ctx.macCipher, ctx.params)
+	if err != nil {
+		return nil, err
+	}
+	return &CipherContext{
+		cipher:      ctx.cipher,
+		mode:        mode,
+		paddingMode: ctx.paddingMode,
+		cipherMode:  ctx.cipherMode,
+		iv:          iv,
+		keySize:     ctx.keySize,
+		macCipher:   ctx.macCipher,
+		params:      ctx.params,
+	}, nil
+}
+
+func buildMode(
+	cipher interfaces.BlockCipher,
+	cipherMode modes.CipherMode,
+	iv []byte,
+	sivMACCipher interfaces.BlockCipher,
+	params []interface{},
+) (modes.Mode, error) {
 	var mode modes.Mode
 	switch cipherMode {
 	case modes.ECB:
@@ -61,12 +214,12 @@ func NewCipherContext(
 		if iv == nil {
 			return nil, fmt.Errorf("CFB mode requires IV")
 		}
-		mode = modes.NewCFBMode(iv)
+		mode = modes.NewCFBSegmentMode(iv, segmentSize(params))
 	case modes.OFB:
 		if iv == nil {
 			return nil, fmt.Errorf("OFB mode requires IV")
 		}
-		mode = modes.NewOFBMode(iv)
+		mode = modes.NewOFBSegmentMode(iv, segmentSize(params))
 	case modes.CTR:
 		if iv == nil {
 			return nil, fmt.Errorf("CTR mode requires IV")
@@ -77,118 +230,301 @@ func NewCipherContext(
 			return nil, fmt.Errorf("RandomDelta mode requires IV")
 		}
 		mode = modes.NewRandomDeltaMode(iv)
+	case modes.GCM:
+		if iv == nil {
+			return nil, fmt.Errorf("GCM mode requires nonce")
+		}
+		mode = modes.NewGCMMode(iv, associatedData(params))
+	case modes.CCM:
+		if iv == nil {
+			return nil, fmt.Errorf("CCM mode requires nonce")
+		}
+		ccm, err := modes.NewCCMMode(iv, tagSize(params, modes.CCMDefaultTag), associatedData(params))
+		if err != nil {
+			return nil, err
+		}
+		mode = ccm
+	case modes.EAX:
+		if iv == nil {
+			return nil, fmt.Errorf("EAX mode requires nonce")
+		}
+		eax, err := modes.NewEAXMode(iv, tagSize(params, cipher.BlockSize()), associatedData(params))
+		if err != nil {
+			return nil, err
+		}
+		mode = eax
+	case modes.OCB:
+		if iv == nil {
+			return nil, fmt.Errorf("OCB mode requires nonce")
+		}
+		ocb, err := modes.NewOCBMode(iv, tagSize(params, modes.OCBBlockSize), associatedData(params))
+		if err != nil {
+			return nil, err
+		}
+		mode = ocb
+	case modes.SIV:
+		mode = modes.NewSIVMode(sivMACCipher, associatedDataVector(params)...)
+	case modes.CBCCS1, modes.CBCCS2, modes.CBCCS3:
+		if iv == nil {
+			return nil, fmt.Errorf("CBC-CS mode requires IV")
+		}
+		mode = modes.NewCBCCSMode(iv, modes.StealingVariant(cipherMode-modes.CBCCS1)+modes.CS1)
 	default:
 		return nil, fmt.Errorf("unsupported cipher mode: %d", cipherMode)
 	}
 
-	return &CipherContext{
-		cipher:      cipher,
-		mode:        mode,
-		paddingMode: paddingMode,
-		cipherMode:  cipherMode,
-	}, nil
+	return mode, nil
 }
 
+// Encrypt шифрует сообщение; при PerMessageIV перед шифртекстом идет свежий IV
 func (ctx *CipherContext) Encrypt(plaintext []byte) ([]byte, error) {
-	dataToEncrypt := plaintext
-	var err error
-
-	if !isStreamMode(ctx.cipherMode) {
-		dataToEncrypt, err = padding.Pad(plaintext, ctx.cipher.BlockSize(), ctx.paddingMode)
-		if err != nil {
-			return nil, fmt.Errorf("padding failed: %w", err)
-		}
+	msg, prefix, err := ctx.beginMessage()
+	if err != nil {
+		return nil, err
 	}
-
-	ciphertext, err := ctx.mode.Encrypt(ctx.cipher, dataToEncrypt)
+	ciphertext, err := msg.encrypt(plaintext)
 	if err != nil {
-		return nil, fmt.Errorf("encryption failed: %w", err)
+		return nil, err
 	}
-
-	return ciphertext, nil
+	return append(prefix, ciphertext...), nil
 }
 
 func (ctx *CipherContext) Decrypt(ciphertext []byte) ([]byte, error) {
-	decryptedData, err := ctx.mode.Decrypt(ctx.cipher, ciphertext)
+	if ctx.ivPolicy != PerMessageIV {
+		return ctx.decrypt(ciphertext)
+	}
+	size := len(ctx.iv)
+	if len(ciphertext) < size {
+		return nil, fmt.Errorf("ciphertext too short for %d-byte IV prefix", size)
+	}
+	msg, err := ctx.withIV(ciphertext[:size])
 	if err != nil {
-		return nil, fmt.Errorf("decryption failed: %w", err)
+		return nil, err
 	}
+	return msg.decrypt(ciphertext[size:])
+}
 
-	if !isStreamMode(ctx.cipherMode) {
-		plaintext, err := padding.Unpad(decryptedData, ctx.paddingMode)
-		if err != nil {
-			return nil, fmt.Errorf("unpadding failed: %w", err)
-		}
-		return plaintext, nil
+// newStream создает инкрементальный шифратор (расшифровщик) режима контекста;
+// набивка нужна только блочным режимам
+func (ctx *CipherContext) newStream(encrypt bool) (*modes.Stream, error) {
+	paddingMode := ctx.paddingMode
+	if isStreamMode(ctx.cipherMode) {
+		paddingMode = padding.None
+	}
+	if encrypt {
+		return modes.NewEncrypter(ctx.cipher, ctx.mode, paddingMode)
 	}
+	return modes.NewDecrypter(ctx.cipher, ctx.mode, paddingMode)
+}
 
-	return decryptedData, nil
+func (ctx *CipherContext) encrypt(plaintext []byte) ([]byte, error) {
+	return ctx.process(plaintext, true, "encryption failed: %w")
 }
 
-func (ctx *CipherContext) EncryptAsync(plaintext []byte) <-chan CipherResult {
-	resultChan := make(chan CipherResult, 1)
-	go func() {
-		defer close(resultChan)
-		data, err := ctx.Encrypt(plaintext)
-		resultChan <- CipherResult{Data: data, Err: err}
-	}()
-	return resultChan
+func (ctx *CipherContext) decrypt(ciphertext []byte) ([]byte, error) {
+	return ctx.process(ciphertext, false, "decryption failed: %w")
 }
 
-func (ctx *CipherContext) DecryptAsync(ciphertext []byte) <-chan CipherResult {
+func (ctx *CipherContext) process(data []byte, encrypt bool, errFormat string) ([]byte, error) {
+	stream, err := ctx.newStream(encrypt)
+	if err != nil {
+		return nil, err
+	}
+	out, err := stream.Process(nil, data)
+	if err != nil {
+		return nil, fmt.Errorf(errFormat, err)
+	}
+	return out, nil
+}
+
+// EncryptAsync шифрует в фоне фрагментами, проверяя отмену c между ними; progress может быть nil
+func (ctx *CipherContext) EncryptAsync(c stdcontext.Context, plaintext []byte, progress ProgressFunc) <-chan CipherResult {
+	return ctx.processAsync(c, plaintext, progress, ctx.EncryptStream)
+}
+
+func (ctx *CipherContext) DecryptAsync(c stdcontext.Context, ciphertext []byte, progress ProgressFunc) <-chan CipherResult {
+	return ctx.processAsync(c, ciphertext, progress, ctx.DecryptStream)
+}
+
+func (ctx *CipherContext) processAsync(
+	c stdcontext.Context,
+	data []byte,
+	progress ProgressFunc,
+	stream func(dst io.Writer, src io.Reader) error,
+) <-chan CipherResult {
 	resultChan := make(chan CipherResult, 1)
 	go func() {
 		defer close(resultChan)
-		data, err := ctx.Decrypt(ciphertext)
-		resultChan <- CipherResult{Data: data, Err: err}
+		var output bytes.Buffer
+		err := stream(&output, newProgressReader(c, bytes.NewReader(data), int64(len(data)), progress))
+		if err != nil {
+			if c.Err() != nil {
+				err = c.Err()
+			}
+			resultChan <- CipherResult{Err: err}
+			return
+		}
+		resultChan <- CipherResult{Data: output.Bytes()}
 	}()
 	return resultChan
 }
 
-func (ctx *CipherContext) EncryptFile(inputPath, outputPath string) error {
-	plaintext, err := os.ReadFile(inputPath)
+// streamChunkSize размер фрагмента, которым EncryptStream/DecryptStream читают данные
+var streamChunkSize = 64 * 1024
+
+// processStream прогоняет src через Stream фрагментами по streamChunkSize байт
+func (ctx *CipherContext) processStream(dst io.Writer, src io.Reader, encrypt bool, errFormat string) error {
+	stream, err := ctx.newStream(encrypt)
 	if err != nil {
-		return fmt.Errorf("failed to read input file: %w", err)
+		return err
+	}
+
+	buf := make([]byte, streamChunkSize)
+	var out []byte
+	for {
+		n, readErr := src.Read(buf)
+		if n > 0 {
+			if out, err = stream.Update(out[:0], buf[:n]); err != nil {
+				return fmt.Errorf(errFormat, err)
+			}
+			if _, err := dst.Write(out); err != nil {
+				return fmt.Errorf("failed to write output: %w", err)
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return fmt.Errorf("failed to read input: %w", readErr)
+		}
 	}
-	ciphertext, err := ctx.Encrypt(plaintext)
+
+	if out, err = stream.Final(); err != nil {
+		return fmt.Errorf(errFormat, err)
+	}
+	if _, err := dst.Write(out); err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+	return nil
+}
+
+// EncryptStream шифрует src в dst по фрагментам, набивка добавляется только к последнему
+func (ctx *CipherContext) EncryptStream(dst io.Writer, src io.Reader) error {
+	msg, prefix, err := ctx.beginMessage()
 	if err != nil {
 		return err
 	}
-	if err := os.WriteFile(outputPath, ciphertext, 0644); err != nil {
-		return fmt.Errorf("failed to write output file: %w", err)
+	if _, err := dst.Write(prefix); err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
 	}
-	return nil
+	return msg.encryptStream(dst, src)
+}
+
+// DecryptStream расшифровывает src в dst по фрагментам, набивка снимается только с последнего
+func (ctx *CipherContext) DecryptStream(dst io.Writer, src io.Reader) error {
+	if ctx.ivPolicy != PerMessageIV {
+		return ctx.decryptStream(dst, src)
+	}
+	iv := make([]byte, len(ctx.iv))
+	if _, err := io.ReadFull(src, iv); err != nil {
+		return fmt.Errorf("failed to read IV prefix: %w", err)
+	}
+	msg, err := ctx.withIV(iv)
+	if err != nil {
+		return err
+	}
+	return msg.decryptStream(dst, src)
+}
+
+func (ctx *CipherContext) encryptStream(dst io.Writer, src io.Reader) error {
+	return ctx.processStream(dst, src, true, "encryption failed: %w")
+}
+
+func (ctx *CipherContext) decryptStream(dst io.Writer, src io.Reader) error {
+	return ctx.processStream(dst, src, false, "decryption failed: %w")
+}
+
+func (ctx *CipherContext) EncryptFile(inputPath, outputPath string) error {
+	return ctx.EncryptFileContext(stdcontext.Background(), inputPath, outputPath, nil)
 }
 
 func (ctx *CipherContext) DecryptFile(inputPath, outputPath string) error {
-	ciphertext, err := os.ReadFile(inputPath)
+	return ctx.DecryptFileContext(stdcontext.Background(), inputPath, outputPath, nil)
+}
+
+// EncryptFileContext шифрует файл в контейнер (см. container.go) с возможностью отмены через c;
+// при отмене или ошибке недописанный выходной файл удаляется, а при отмене возвращается c.Err()
+func (ctx *CipherContext) EncryptFileContext(c stdcontext.Context, inputPath, outputPath string, progress ProgressFunc) error {
+	return processFile(c, inputPath, outputPath, progress, ctx.encryptContainer)
+}
+
+func (ctx *CipherContext) DecryptFileContext(c stdcontext.Context, inputPath, outputPath string, progress ProgressFunc) error {
+	return processFile(c, inputPath, outputPath, progress, ctx.decryptContainer)
+}
+
+func processFile(
+	c stdcontext.Context,
+	inputPath, outputPath string,
+	progress ProgressFunc,
+	stream func(dst io.Writer, src io.Reader) error,
+) error {
+	if err := c.Err(); err != nil {
+		return err
+	}
+
+	input, err := os.Open(inputPath)
 	if err != nil {
 		return fmt.Errorf("failed to read input file: %w", err)
 	}
-	plaintext, err := ctx.Decrypt(ciphertext)
+	defer input.Close()
+
+	total := int64(-1)
+	if info, err := input.Stat(); err == nil {
+		total = info.Size()
+	}
+
+	output, err := os.OpenFile(outputPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
 	if err != nil {
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
+
+	fail := func(err error) error {
+		output.Close()
+		os.Remove(outputPath)
+		if c.Err() != nil {
+			return c.Err()
+		}
 		return err
 	}
-	if err := os.WriteFile(outputPath, plaintext, 0644); err != nil {
+
+	writer := bufio.NewWriter(output)
+	if err := stream(writer, newProgressReader(c, input, total, progress)); err != nil {
+		return fail(err)
+	}
+	if err := writer.Flush(); err != nil {
+		return fail(fmt.Errorf("failed to write output file: %w", err))
+	}
+	if err := output.Close(); err != nil {
+		os.Remove(outputPath)
 		return fmt.Errorf("failed to write output file: %w", err)
 	}
 	return nil
 }
 
-func (ctx *CipherContext) EncryptFileAsync(inputPath, outputPath string) <-chan error {
+func (ctx *CipherContext) EncryptFileAsync(c stdcontext.Context, inputPath, outputPath string, progress ProgressFunc) <-chan error {
 	errChan := make(chan error, 1)
 	go func() {
 		defer close(errChan)
-		errChan <- ctx.EncryptFile(inputPath, outputPath)
+		errChan <- ctx.EncryptFileContext(c, inputPath, outputPath, progress)
 	}()
 	return errChan
 }
 
-func (ctx *CipherContext) DecryptFileAsync(inputPath, outputPath string) <-chan error {
+func (ctx *CipherContext) DecryptFileAsync(c stdcontext.Context, inputPath, outputPath string, progress ProgressFunc) <-chan error {
 	errChan := make(chan error, 1)
 	go func() {
 		defer close(errChan)
-		errChan <- ctx.DecryptFile(inputPath, outputPath)
+		errChan <- ctx.DecryptFileContext(c, inputPath, outputPath, progress)
 	}()
 	return errChan
 }
@@ -2,12 +2,22 @@ package context
 
 import (
 	"bytes"
+	stdcontext "context"
 	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
 	"testing"
+	"testing/iotest"
 
+	"github.com/Qwental/crypota/internal/deal"
 	"github.com/Qwental/crypota/internal/des"
+	"github.com/Qwental/crypota/internal/interfaces"
 	"github.com/Qwental/crypota/internal/modes"
 	"github.com/Qwental/crypota/internal/padding"
+	"github.com/Qwental/crypota/internal/rijndael"
 )
 
 func TestCipherContextBasic(t *testing.T) {
@@ -81,3 +91,773 @@ func TestCipherContextCBC(t *testing.T) {
 		t.Errorf("Decryption failed")
 	}
 }
+
+var allStreamModes = []struct {
+	mode modes.CipherMode
+	name string
+}{
+	{modes.ECB, "ECB"},
+	{modes.CBC, "CBC"},
+	{modes.PCBC, "PCBC"},
+	{modes.CFB, "CFB"},
+	{modes.OFB, "OFB"},
+	{modes.CTR, "CTR"},
+	{modes.RandomDelta, "RandomDelta"},
+}
+
+// useSmallChunks уменьшает размер фрагмента, чтобы тесты проходили через границы фрагментов быстро
+func useSmallChunks(t *testing.T) {
+	t.Helper()
+	saved := streamChunkSize
+	streamChunkSize = 256
+	t.Cleanup(func() { streamChunkSize = saved })
+}
+
+func TestCipherContextStreamMatchesOneShot(t *testing.T) {
+	useSmallChunks(t)
+	key := []byte{0x13, 0x34, 0x57, 0x79, 0x9B, 0xBC, 0xDF, 0xF1}
+	iv := make([]byte, 8)
+	rand.Read(iv)
+
+	sizes := []int{0, 5, streamChunkSize, streamChunkSize + 1, 2*streamChunkSize + 13}
+
+	for _, m := range allStreamModes {
+		for _, size := range sizes {
+			t.Run(fmt.Sprintf("%s-%d", m.name, size), func(t *testing.T) {
+				plaintext := make([]byte, size)
+				rand.Read(plaintext)
+
+				// отдельные контексты: повторное шифрование с тем же IV в потоковом режиме запрещено
+				newContext := func() *CipherContext {
+					ctx, err := NewCipherContext(des.NewDESCipher(), key, m.mode, padding.PKCS7, iv)
+					if err != nil {
+						t.Fatalf("NewCipherContext failed: %v", err)
+					}
+					return ctx
+				}
+				ctx := newContext()
+
+				expected, err := newContext().Encrypt(plaintext)
+				if err != nil {
+					t.Fatalf("Encrypt failed: %v", err)
+				}
+
+				var encrypted bytes.Buffer
+				if err := ctx.EncryptStream(&encrypted, bytes.NewReader(plaintext)); err != nil {
+					t.Fatalf("EncryptStream failed: %v", err)
+				}
+				if !bytes.Equal(encrypted.Bytes(), expected) {
+					t.Fatalf("Stream ciphertext differs from one-shot ciphertext")
+				}
+
+				var decrypted bytes.Buffer
+				if err := ctx.DecryptStream(&decrypted, bytes.NewReader(encrypted.Bytes())); err != nil {
+					t.Fatalf("DecryptStream failed: %v", err)
+				}
+				if !bytes.Equal(decrypted.Bytes(), plaintext) {
+					t.Errorf("Stream round-trip failed")
+				}
+			})
+		}
+	}
+}
+
+func TestCipherContextFileRoundTrip(t *testing.T) {
+	useSmallChunks(t)
+	key := []byte{0x13, 0x34, 0x57, 0x79, 0x9B, 0xBC, 0xDF, 0xF1}
+	iv := make([]byte, 8)
+	rand.Read(iv)
+
+	dir := t.TempDir()
+	inputPath := filepath.Join(dir, "input.bin")
+	encryptedPath := filepath.Join(dir, "input.bin.enc")
+	decryptedPath := filepath.Join(dir, "input.bin.dec")
+
+	plaintext := make([]byte, 3*streamChunkSize+7)
+	rand.Read(plaintext)
+	if err := os.WriteFile(inputPath, plaintext, 0644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	ctx, err := NewCipherContext(des.NewDESCipher(), key, modes.CBC, padding.PKCS7, iv)
+	if err != nil {
+		t.Fatalf("NewCipherContext failed: %v", err)
+	}
+
+	if err := <-ctx.EncryptFileAsync(stdcontext.Background(), inputPath, encryptedPath, nil); err != nil {
+		t.Fatalf("EncryptFile failed: %v", err)
+	}
+	if err := <-ctx.DecryptFileAsync(stdcontext.Background(), encryptedPath, decryptedPath, nil); err != nil {
+		t.Fatalf("DecryptFile failed: %v", err)
+	}
+
+	decrypted, err := os.ReadFile(decryptedPath)
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	if !bytes.Equal(decrypted, plaintext) {
+		t.Errorf("File round-trip failed")
+	}
+}
+
+func TestCipherContextGCM(t *testing.T) {
+	key := make([]byte, 16)
+	rand.Read(key)
+	nonce := make([]byte, 12)
+	rand.Read(nonce)
+	aad := []byte("file header")
+	plaintext := []byte("Authenticated message for GCM mode")
+
+	cipher, err := rijndael.NewRijndaelCipher(16, 16, 0x1B)
+	if err != nil {
+		t.Fatalf("NewRijndaelCipher failed: %v", err)
+	}
+
+	ctx, err := NewCipherContext(cipher, key, modes.GCM, padding.PKCS7, nonce, aad)
+	if err != nil {
+		t.Fatalf("NewCipherContext failed: %v", err)
+	}
+
+	ciphertext, err := ctx.Encrypt(plaintext)
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+	if len(ciphertext) != len(plaintext)+modes.GCMTagSize {
+		t.Errorf("Unexpected ciphertext length %d", len(ciphertext))
+	}
+
+	decrypted, err := ctx.Decrypt(ciphertext)
+	if err != nil {
+		t.Fatalf("Decrypt failed: %v", err)
+	}
+	if !bytes.Equal(decrypted, plaintext) {
+		t.Errorf("Decryption failed")
+	}
+
+	ciphertext[len(ciphertext)-1] ^= 0xFF
+	if _, err := ctx.Decrypt(ciphertext); !errors.Is(err, modes.ErrAuthenticationFailed) {
+		t.Errorf("Expected ErrAuthenticationFailed, got %v", err)
+	}
+
+	if _, err := ctx.Encrypt(plaintext); !errors.Is(err, ErrIVReuse) {
+		t.Errorf("Expected ErrIVReuse for second encryption with the same nonce, got %v", err)
+	}
+
+	ctx, _ = NewCipherContext(cipher, key, modes.GCM, padding.PKCS7, nonce, aad)
+	var encrypted bytes.Buffer
+	if err := ctx.EncryptStream(&encrypted, bytes.NewReader(plaintext)); err != nil {
+		t.Fatalf("EncryptStream failed: %v", err)
+	}
+	var streamed bytes.Buffer
+	if err := ctx.DecryptStream(&streamed, &encrypted); err != nil {
+		t.Fatalf("DecryptStream failed: %v", err)
+	}
+	if !bytes.Equal(streamed.Bytes(), plaintext) {
+		t.Errorf("Stream round-trip failed")
+	}
+}
+
+func TestCipherContextCCM(t *testing.T) {
+	key := make([]byte, 16)
+	rand.Read(key)
+	nonce := make([]byte, 13)
+	rand.Read(nonce)
+	plaintext := []byte("Short CCM message")
+
+	cipher, err := rijndael.NewRijndaelCipher(16, 16, 0x1B)
+	if err != nil {
+		t.Fatalf("NewRijndaelCipher failed: %v", err)
+	}
+
+	ctx, err := NewCipherContext(cipher, key, modes.CCM, padding.PKCS7, nonce, []byte("aad"), modes.TagSize(8))
+	if err != nil {
+		t.Fatalf("NewCipherContext failed: %v", err)
+	}
+
+	ciphertext, err := ctx.Encrypt(plaintext)
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+	if len(ciphertext) != len(plaintext)+8 {
+		t.Errorf("Unexpected ciphertext length %d", len(ciphertext))
+	}
+
+	decrypted, err := ctx.Decrypt(ciphertext)
+	if err != nil {
+		t.Fatalf("Decrypt failed: %v", err)
+	}
+	if !bytes.Equal(decrypted, plaintext) {
+		t.Errorf("Decryption failed")
+	}
+
+	if _, err := NewCipherContext(cipher, key, modes.CCM, padding.PKCS7, nonce, modes.TagSize(3)); err == nil {
+		t.Error("Expected error for invalid tag size")
+	}
+}
+
+func TestCipherContextAEADModes(t *testing.T) {
+	key := make([]byte, 16)
+	rand.Read(key)
+	nonce := make([]byte, 12)
+	rand.Read(nonce)
+	plaintext := []byte("Message protected by an AEAD mode")
+
+	for _, m := range []struct {
+		mode modes.CipherMode
+		name string
+	}{
+		{modes.EAX, "EAX"},
+		{modes.OCB, "OCB"},
+	} {
+		t.Run(m.name, func(t *testing.T) {
+			cipher, err := deal.NewDEALCipher(16)
+			if err != nil {
+				t.Fatalf("NewDEALCipher failed: %v", err)
+			}
+			ctx, err := NewCipherContext(cipher, key, m.mode, padding.PKCS7, nonce, []byte("aad"))
+			if err != nil {
+				t.Fatalf("NewCipherContext failed: %v", err)
+			}
+
+			ciphertext, err := ctx.Encrypt(plaintext)
+			if err != nil {
+				t.Fatalf("Encrypt failed: %v", err)
+			}
+			decrypted, err := ctx.Decrypt(ciphertext)
+			if err != nil {
+				t.Fatalf("Decrypt failed: %v", err)
+			}
+			if !bytes.Equal(decrypted, plaintext) {
+				t.Errorf("Decryption failed")
+			}
+
+			ciphertext[0] ^= 0x01
+			if _, err := ctx.Decrypt(ciphertext); !errors.Is(err, modes.ErrAuthenticationFailed) {
+				t.Errorf("Expected ErrAuthenticationFailed, got %v", err)
+			}
+		})
+	}
+}
+
+func TestCipherContextSIV(t *testing.T) {
+	key := make([]byte, 32)
+	rand.Read(key)
+	plaintext := []byte(`{"listen": ":8080", "workers": 4}`)
+
+	newContext := func() *CipherContext {
+		cipher, _ := rijndael.NewRijndaelCipher(16, 16, 0x1B)
+		macCipher, _ := rijndael.NewRijndaelCipher(16, 16, 0x1B)
+		ctx, err := NewCipherContext(cipher, key, modes.SIV, padding.PKCS7, nil, macCipher, []byte("config"), []byte("v1"))
+		if err != nil {
+			t.Fatalf("NewCipherContext failed: %v", err)
+		}
+		return ctx
+	}
+
+	first, err := newContext().Encrypt(plaintext)
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+	second, err := newContext().Encrypt(plaintext)
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+	if !bytes.Equal(first, second) {
+		t.Errorf("SIV encryption must be deterministic")
+	}
+
+	decrypted, err := newContext().Decrypt(first)
+	if err != nil {
+		t.Fatalf("Decrypt failed: %v", err)
+	}
+	if !bytes.Equal(decrypted, plaintext) {
+		t.Errorf("Decryption failed")
+	}
+
+	cipher, _ := rijndael.NewRijndaelCipher(16, 16, 0x1B)
+	if _, err := NewCipherContext(cipher, key, modes.SIV, padding.PKCS7, nil); err == nil {
+		t.Error("Expected error without MAC cipher instance")
+	}
+}
+
+func TestCipherContextCiphertextStealing(t *testing.T) {
+	useSmallChunks(t)
+	key := []byte{0x13, 0x34, 0x57, 0x79, 0x9B, 0xBC, 0xDF, 0xF1}
+	iv := make([]byte, 8)
+	rand.Read(iv)
+
+	csModes := []struct {
+		mode modes.CipherMode
+		name string
+	}{
+		{modes.CBCCS1, "CBC-CS1"},
+		{modes.CBCCS2, "CBC-CS2"},
+		{modes.CBCCS3, "CBC-CS3"},
+	}
+
+	for _, m := range csModes {
+		for _, size := range []int{8, 13, 64, streamChunkSize + 5} {
+			t.Run(fmt.Sprintf("%s-%d", m.name, size), func(t *testing.T) {
+				plaintext := make([]byte, size)
+				rand.Read(plaintext)
+
+				ctx, err := NewCipherContext(des.NewDESCipher(), key, m.mode, padding.None, iv)
+				if err != nil {
+					t.Fatalf("NewCipherContext failed: %v", err)
+				}
+				ciphertext, err := ctx.Encrypt(plaintext)
+				if err != nil {
+					t.Fatalf("Encrypt failed: %v", err)
+				}
+				if len(ciphertext) != size {
+					t.Errorf("Ciphertext length %d, want %d", len(ciphertext), size)
+				}
+
+				var decrypted bytes.Buffer
+				if err := ctx.DecryptStream(&decrypted, bytes.NewReader(ciphertext)); err != nil {
+					t.Fatalf("DecryptStream failed: %v", err)
+				}
+				if !bytes.Equal(decrypted.Bytes(), plaintext) {
+					t.Errorf("Round-trip failed")
+				}
+			})
+		}
+	}
+
+	ctx, err := NewCipherContext(des.NewDESCipher(), key, modes.CBCCS1, padding.None, iv)
+	if err != nil {
+		t.Fatalf("NewCipherContext failed: %v", err)
+	}
+	if _, err := ctx.Encrypt(make([]byte, 7)); err == nil {
+		t.Error("Expected error for input shorter than a block")
+	}
+	if _, err := NewCipherContext(des.NewDESCipher(), key, modes.CBCCS2, padding.None, nil); err == nil {
+		t.Error("Expected error without IV")
+	}
+	for _, m := range csModes {
+		if _, err := NewCipherContext(des.NewDESCipher(), key, m.mode, padding.PKCS7, iv); err == nil {
+			t.Errorf("%s: expected error for PKCS7 padding", m.name)
+		}
+	}
+}
+
+func TestCipherContextSegmentModes(t *testing.T) {
+	useSmallChunks(t)
+	key := []byte{0x13, 0x34, 0x57, 0x79, 0x9B, 0xBC, 0xDF, 0xF1}
+	iv := make([]byte, 8)
+	rand.Read(iv)
+	plaintext := make([]byte, 3*streamChunkSize+7)
+	rand.Read(plaintext)
+
+	for _, m := range allStreamModes[3:5] {
+		for _, s := range []int{1, 8, 12, 40} {
+			t.Run(fmt.Sprintf("%s-%d", m.name, s), func(t *testing.T) {
+				newContext := func() *CipherContext {
+					ctx, err := NewCipherContext(des.NewDESCipher(), key, m.mode, padding.PKCS7, iv, modes.SegmentSize(s))
+					if err != nil {
+						t.Fatalf("NewCipherContext failed: %v", err)
+					}
+					return ctx
+				}
+				ctx := newContext()
+				expected, err := newContext().Encrypt(plaintext)
+				if err != nil {
+					t.Fatalf("Encrypt failed: %v", err)
+				}
+
+				var encrypted bytes.Buffer
+				if err := ctx.EncryptStream(&encrypted, bytes.NewReader(plaintext)); err != nil {
+					t.Fatalf("EncryptStream failed: %v", err)
+				}
+				if !bytes.Equal(encrypted.Bytes(), expected) {
+					t.Fatalf("Stream ciphertext differs from one-shot ciphertext")
+				}
+
+				var decrypted bytes.Buffer
+				if err := ctx.DecryptStream(&decrypted, bytes.NewReader(expected)); err != nil {
+					t.Fatalf("DecryptStream failed: %v", err)
+				}
+				if !bytes.Equal(decrypted.Bytes(), plaintext) {
+					t.Errorf("Stream round-trip failed")
+				}
+			})
+		}
+	}
+}
+
+func TestCipherContextFileProgressAndCancel(t *testing.T) {
+	useSmallChunks(t)
+	key := []byte{0x13, 0x34, 0x57, 0x79, 0x9B, 0xBC, 0xDF, 0xF1}
+	iv := make([]byte, 8)
+	rand.Read(iv)
+
+	dir := t.TempDir()
+	inputPath := filepath.Join(dir, "input.bin")
+	outputPath := filepath.Join(dir, "input.bin.enc")
+	plaintext := make([]byte, 10*streamChunkSize+3)
+	rand.Read(plaintext)
+	if err := os.WriteFile(inputPath, plaintext, 0644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	ctx, err := NewCipherContext(des.NewDESCipher(), key, modes.CTR, padding.PKCS7, iv)
+	if err != nil {
+		t.Fatalf("NewCipherContext failed: %v", err)
+	}
+
+	var last int64
+	err = <-ctx.EncryptFileAsync(stdcontext.Background(), inputPath, outputPath, func(processed, total int64) {
+		if total != int64(len(plaintext)) {
+			t.Errorf("Total %d, want %d", total, len(plaintext))
+		}
+		if processed < last {
+			t.Errorf("Progress went backwards: %d after %d", processed, last)
+		}
+		last = processed
+	})
+	if err != nil {
+		t.Fatalf("EncryptFileAsync failed: %v", err)
+	}
+	if last != int64(len(plaintext)) {
+		t.Errorf("Final progress %d, want %d", last, len(plaintext))
+	}
+
+	ctx, _ = NewCipherContext(des.NewDESCipher(), key, modes.CTR, padding.PKCS7, iv)
+	c, cancel := stdcontext.WithCancel(stdcontext.Background())
+	defer cancel()
+	err = <-ctx.EncryptFileAsync(c, inputPath, outputPath, func(processed, total int64) {
+		if processed > 2*int64(streamChunkSize) {
+			cancel()
+		}
+	})
+	if !errors.Is(err, stdcontext.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+	if _, err := os.Stat(outputPath); !os.IsNotExist(err) {
+		t.Errorf("Partial output file must be removed, stat error: %v", err)
+	}
+
+	if err := ctx.DecryptFileContext(c, inputPath, outputPath, nil); !errors.Is(err, stdcontext.Canceled) {
+		t.Errorf("Expected context.Canceled for already cancelled context, got %v", err)
+	}
+}
+
+func TestCipherContextAsyncCancel(t *testing.T) {
+	useSmallChunks(t)
+	key := []byte{0x13, 0x34, 0x57, 0x79, 0x9B, 0xBC, 0xDF, 0xF1}
+	iv := make([]byte, 8)
+	rand.Read(iv)
+	plaintext := make([]byte, 4*streamChunkSize)
+	rand.Read(plaintext)
+
+	ctx, err := NewCipherContext(des.NewDESCipher(), key, modes.CBC, padding.PKCS7, iv)
+	if err != nil {
+		t.Fatalf("NewCipherContext failed: %v", err)
+	}
+
+	encrypted := <-ctx.EncryptAsync(stdcontext.Background(), plaintext, nil)
+	if encrypted.Err != nil {
+		t.Fatalf("EncryptAsync failed: %v", encrypted.Err)
+	}
+	expected, _ := ctx.Encrypt(plaintext)
+	if !bytes.Equal(encrypted.Data, expected) {
+		t.Errorf("EncryptAsync result differs from Encrypt")
+	}
+	decrypted := <-ctx.DecryptAsync(stdcontext.Background(), encrypted.Data, nil)
+	if decrypted.Err != nil || !bytes.Equal(decrypted.Data, plaintext) {
+		t.Errorf("DecryptAsync round-trip failed: %v", decrypted.Err)
+	}
+
+	c, cancel := stdcontext.WithCancel(stdcontext.Background())
+	cancel()
+	if result := <-ctx.EncryptAsync(c, plaintext, nil); !errors.Is(result.Err, stdcontext.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", result.Err)
+	}
+}
+
+func TestCipherContextFileContainer(t *testing.T) {
+	key := []byte{0x13, 0x34, 0x57, 0x79, 0x9B, 0xBC, 0xDF, 0xF1}
+	iv := make([]byte, 8)
+	rand.Read(iv)
+
+	dir := t.TempDir()
+	inputPath := filepath.Join(dir, "input.bin")
+	encryptedPath := filepath.Join(dir, "input.bin.enc")
+	decryptedPath := filepath.Join(dir, "input.bin.dec")
+	plaintext := []byte("container header carries algorithm, mode, padding and IV")
+	if err := os.WriteFile(inputPath, plaintext, 0644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	ctx, err := NewCipherContext(des.NewDESCipher(), key, modes.CFB, padding.PKCS7, iv, modes.SegmentSize(8))
+	if err != nil {
+		t.Fatalf("NewCipherContext failed: %v", err)
+	}
+	if err := ctx.EncryptFile(inputPath, encryptedPath); err != nil {
+		t.Fatalf("EncryptFile failed: %v", err)
+	}
+
+	file, err := os.Open(encryptedPath)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	header, err := ReadFileHeader(file)
+	file.Close()
+	if err != nil {
+		t.Fatalf("ReadFileHeader failed: %v", err)
+	}
+	if header.Cipher != interfaces.CipherDES || header.KeySize != 8 || header.BlockSize != 8 ||
+		header.Mode != modes.CFB || header.Padding != padding.PKCS7 || header.SegmentBits != 8 ||
+		!bytes.Equal(header.IV, iv) {
+		t.Errorf("Unexpected header: %+v", header)
+	}
+
+	// контекст с другим IV все равно расшифрует файл - IV берется из заголовка
+	otherIV := make([]byte, 8)
+	other, _ := NewCipherContext(des.NewDESCipher(), key, modes.CFB, padding.PKCS7, otherIV, modes.SegmentSize(8))
+	if err := other.DecryptFile(encryptedPath, decryptedPath); err != nil {
+		t.Fatalf("DecryptFile failed: %v", err)
+	}
+	decrypted, _ := os.ReadFile(decryptedPath)
+	if !bytes.Equal(decrypted, plaintext) {
+		t.Errorf("Container round-trip failed")
+	}
+
+	wrongMode, _ := NewCipherContext(des.NewDESCipher(), key, modes.OFB, padding.PKCS7, iv)
+	if err := wrongMode.DecryptFile(encryptedPath, decryptedPath); err == nil {
+		t.Error("Expected error for mode mismatch")
+	}
+
+	// файл без заголовка по умолчанию отвергается
+	ctx, _ = NewCipherContext(des.NewDESCipher(), key, modes.CFB, padding.PKCS7, iv, modes.SegmentSize(8))
+	raw, _ := ctx.Encrypt(plaintext)
+	rawPath := filepath.Join(dir, "raw.enc")
+	os.WriteFile(rawPath, raw, 0644)
+	if err := ctx.DecryptFile(rawPath, decryptedPath); !errors.Is(err, ErrNotContainer) {
+		t.Errorf("Expected ErrNotContainer for raw file, got %v", err)
+	}
+
+	// с AllowRawFiles он расшифровывается как сырой шифртекст
+	ctx, _ = NewCipherContext(des.NewDESCipher(), key, modes.CFB, padding.PKCS7, iv, modes.SegmentSize(8), AllowRawFiles)
+	if err := ctx.DecryptFile(rawPath, decryptedPath); err != nil {
+		t.Fatalf("DecryptFile for raw ciphertext failed: %v", err)
+	}
+	decrypted, _ = os.ReadFile(decryptedPath)
+	if !bytes.Equal(decrypted, plaintext) {
+		t.Errorf("Raw ciphertext round-trip failed")
+	}
+
+	if _, err := ReadFileHeader(bytes.NewReader(raw)); !errors.Is(err, ErrNotContainer) {
+		t.Errorf("Expected ErrNotContainer, got %v", err)
+	}
+
+	// ошибка чтения - не повод считать файл сырым
+	readErr := errors.New("disk failure")
+	if err := ctx.decryptContainer(io.Discard, iotest.ErrReader(readErr)); !errors.Is(err, readErr) {
+		t.Errorf("Expected read error to propagate, got %v", err)
+	}
+}
+
+func TestCipherContextPerMessageIV(t *testing.T) {
+	useSmallChunks(t)
+	key := make([]byte, 16)
+	rand.Read(key)
+	plaintext := make([]byte, 2*streamChunkSize+9)
+	rand.Read(plaintext)
+
+	for _, m := range []struct {
+		mode   modes.CipherMode
+		name   string
+		ivSize int
+	}{
+		{modes.CBC, "CBC", 16},
+		{modes.CTR, "CTR", 16},
+		{modes.OFB, "OFB", 16},
+		{modes.GCM, "GCM", modes.GCMStandardNonce},
+		{modes.OCB, "OCB", 12},
+	} {
+		t.Run(m.name, func(t *testing.T) {
+			cipher, _ := rijndael.NewRijndaelCipher(16, 16, 0x1B)
+			ctx, err := NewCipherContext(cipher, key, m.mode, padding.PKCS7, nil, PerMessageIV)
+			if err != nil {
+				t.Fatalf("NewCipherContext failed: %v", err)
+			}
+
+			first, err := ctx.Encrypt(plaintext)
+			if err != nil {
+				t.Fatalf("Encrypt failed: %v", err)
+			}
+			second, err := ctx.Encrypt(plaintext)
+			if err != nil {
+				t.Fatalf("Second Encrypt failed: %v", err)
+			}
+			if bytes.Equal(first[:m.ivSize], second[:m.ivSize]) || bytes.Equal(first, second) {
+				t.Errorf("Each message must get a fresh IV")
+			}
+
+			for _, ciphertext := range [][]byte{first, second} {
+				decrypted, err := ctx.Decrypt(ciphertext)
+				if err != nil {
+					t.Fatalf("Decrypt failed: %v", err)
+				}
+				if !bytes.Equal(decrypted, plaintext) {
+					t.Errorf("Round-trip failed")
+				}
+			}
+
+			// поток пишет тот же формат: IV, затем шифртекст
+			var encrypted bytes.Buffer
+			if err := ctx.EncryptStream(&encrypted, bytes.NewReader(plaintext)); err != nil {
+				t.Fatalf("EncryptStream failed: %v", err)
+			}
+			decrypted, err := ctx.Decrypt(encrypted.Bytes())
+			if err != nil || !bytes.Equal(decrypted, plaintext) {
+				t.Errorf("Stream output must decrypt with Decrypt: %v", err)
+			}
+			var streamed bytes.Buffer
+			if err := ctx.DecryptStream(&streamed, bytes.NewReader(first)); err != nil {
+				t.Fatalf("DecryptStream failed: %v", err)
+			}
+			if !bytes.Equal(streamed.Bytes(), plaintext) {
+				t.Errorf("DecryptStream of Encrypt output failed")
+			}
+		})
+	}
+
+	// в файле свежий IV попадает в заголовок контейнера
+	cipher, _ := rijndael.NewRijndaelCipher(16, 16, 0x1B)
+	ctx, err := NewCipherContext(cipher, key, modes.CTR, padding.PKCS7, nil, PerMessageIV)
+	if err != nil {
+		t.Fatalf("NewCipherContext failed: %v", err)
+	}
+	dir := t.TempDir()
+	inputPath := filepath.Join(dir, "input.bin")
+	os.WriteFile(inputPath, plaintext, 0644)
+	var ivs [][]byte
+	for i := 0; i < 2; i++ {
+		encryptedPath := filepath.Join(dir, fmt.Sprintf("input.%d.enc", i))
+		decryptedPath := filepath.Join(dir, fmt.Sprintf("input.%d.dec", i))
+		if err := ctx.EncryptFile(inputPath, encryptedPath); err != nil {
+			t.Fatalf("EncryptFile failed: %v", err)
+		}
+		file, _ := os.Open(encryptedPath)
+		header, err := ReadFileHeader(file)
+		file.Close()
+		if err != nil {
+			t.Fatalf("ReadFileHeader failed: %v", err)
+		}
+		ivs = append(ivs, header.IV)
+		if err := ctx.DecryptFile(encryptedPath, decryptedPath); err != nil {
+			t.Fatalf("DecryptFile failed: %v", err)
+		}
+		decrypted, _ := os.ReadFile(decryptedPath)
+		if !bytes.Equal(decrypted, plaintext) {
+			t.Errorf("File round-trip failed")
+		}
+	}
+	if bytes.Equal(ivs[0], ivs[1]) {
+		t.Errorf("Each file must get a fresh IV")
+	}
+
+	if _, err := NewCipherContext(cipher, key, modes.CTR, padding.PKCS7, make([]byte, 16), PerMessageIV); err == nil {
+		t.Error("Expected error for explicit IV with PerMessageIV")
+	}
+	if _, err := NewCipherContext(cipher, key, modes.ECB, padding.PKCS7, nil, PerMessageIV); err == nil {
+		t.Error("Expected error for ECB with PerMessageIV")
+	}
+}
+
+func TestCipherContextIVReuseGuardrail(t *testing.T) {
+	key := []byte{0x13, 0x34, 0x57, 0x79, 0x9B, 0xBC, 0xDF, 0xF1}
+	iv := make([]byte, 8)
+	rand.Read(iv)
+	plaintext := []byte("same keystream twice leaks xor of plaintexts")
+
+	for _, m := range allStreamModes {
+		t.Run(m.name, func(t *testing.T) {
+			ctx, err := NewCipherContext(des.NewDESCipher(), key, m.mode, padding.PKCS7, iv)
+			if err != nil {
+				t.Fatalf("NewCipherContext failed: %v", err)
+			}
+			if _, err := ctx.Encrypt(plaintext); err != nil {
+				t.Fatalf("Encrypt failed: %v", err)
+			}
+
+			_, err = ctx.Encrypt(plaintext)
+			switch m.mode {
+			case modes.CFB, modes.OFB, modes.CTR:
+				if !errors.Is(err, ErrIVReuse) {
+					t.Errorf("Expected ErrIVReuse, got %v", err)
+				}
+				var buf bytes.Buffer
+				if err := ctx.EncryptStream(&buf, bytes.NewReader(plaintext)); !errors.Is(err, ErrIVReuse) {
+					t.Errorf("Expected ErrIVReuse from EncryptStream, got %v", err)
+				}
+			default:
+				if err != nil {
+					t.Errorf("Block modes may reuse the context, got %v", err)
+				}
+			}
+		})
+	}
+}
+
+// batchOnlyDES запрещает поблочные вызовы: ECB и CTR контекста должны идти через BatchCipher
+type batchOnlyDES struct {
+	*des.FastDESCipher
+}
+
+func (c batchOnlyDES) EncryptBlock([]byte) ([]byte, error) {
+	return nil, errors.New("EncryptBlock called instead of EncryptBlocks")
+}
+
+func (c batchOnlyDES) DecryptBlock([]byte) ([]byte, error) {
+	return nil, errors.New("DecryptBlock called instead of DecryptBlocks")
+}
+
+func TestCipherContextBatchDES(t *testing.T) {
+	key := []byte{0x13, 0x34, 0x57, 0x79, 0x9B, 0xBC, 0xDF, 0xF1}
+	iv := make([]byte, 8)
+	rand.Read(iv)
+	plaintext := make([]byte, 1000)
+	rand.Read(plaintext)
+
+	for _, mode := range []modes.CipherMode{modes.ECB, modes.CTR} {
+		paddingMode := padding.PKCS7
+		modeIV := iv
+		if mode == modes.ECB {
+			modeIV = nil
+		} else {
+			paddingMode = padding.None
+		}
+
+		reference, err := NewCipherContext(des.NewDESCipher(), key, mode, paddingMode, modeIV)
+		if err != nil {
+			t.Fatalf("mode %d: NewCipherContext failed: %v", mode, err)
+		}
+		expected, err := reference.Encrypt(plaintext)
+		if err != nil {
+			t.Fatalf("mode %d: reference Encrypt failed: %v", mode, err)
+		}
+
+		batch := batchOnlyDES{des.NewFastDESCipher().(*des.FastDESCipher)}
+		ctx, err := NewCipherContext(batch, key, mode, paddingMode, modeIV)
+		if err != nil {
+			t.Fatalf("mode %d: NewCipherContext failed: %v", mode, err)
+		}
+		ciphertext, err := ctx.Encrypt(plaintext)
+		if err != nil {
+			t.Fatalf("mode %d: batch Encrypt failed: %v", mode, err)
+		}
+		if !bytes.Equal(ciphertext, expected) {
+			t.Errorf("mode %d: batch DES differs from reference DES", mode)
+		}
+		decrypted, err := ctx.Decrypt(ciphertext)
+		if err != nil {
+			t.Fatalf("mode %d: batch Decrypt failed: %v", mode, err)
+		}
+		if !bytes.Equal(decrypted, plaintext) {
+			t.Errorf("mode %d: round-trip failed", mode)
+		}
+	}
+}
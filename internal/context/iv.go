@@ -0,0 +1,78 @@
+package context
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Qwental/crypota/internal/modes"
+)
+
+// IVPolicy определяет, откуда Encrypt берет IV, передается в params контекста
+type IVPolicy int
+
+const (
+	// FixedIV - IV из конструктора; для режимов, где повтор IV повторяет гамму,
+	// второе шифрование тем же контекстом возвращает ErrIVReuse
+	FixedIV IVPolicy = iota
+	// PerMessageIV - каждое сообщение шифруется со свежим IV (modes.GenerateIV),
+	// который записывается перед шифртекстом и считывается оттуда при расшифровании
+	PerMessageIV
+)
+
+// ErrIVReuse - попытка второй раз зашифровать с тем же явно заданным IV в потоковом режиме
+var ErrIVReuse = errors.New("IV reuse: stream mode context already encrypted a message with this IV, use PerMessageIV or a new context")
+
+func ivPolicy(params []interface{}) IVPolicy {
+	for _, p := range params {
+		if policy, ok := p.(IVPolicy); ok {
+			return policy
+		}
+	}
+	return FixedIV
+}
+
+// reusesKeystream - режимы, у которых повтор IV раскрывает xor открытых текстов (или ломает тег)
+func reusesKeystream(m modes.CipherMode) bool {
+	switch m {
+	case modes.CFB, modes.OFB, modes.CTR, modes.GCM, modes.CCM, modes.EAX, modes.OCB:
+		return true
+	default:
+		return false
+	}
+}
+
+// generatedIVSize - длина IV (nonce), которую генерирует PerMessageIV; 0 - режим IV не использует
+func generatedIVSize(m modes.CipherMode, blockSize int) int {
+	switch m {
+	case modes.ECB, modes.SIV:
+		return 0
+	case modes.GCM:
+		return modes.GCMStandardNonce
+	case modes.CCM, modes.OCB:
+		return 12
+	default:
+		return blockSize
+	}
+}
+
+// beginMessage выдает контекст для шифрования очередного сообщения и префикс с его IV
+func (ctx *CipherContext) beginMessage() (*CipherContext, []byte, error) {
+	if ctx.ivPolicy != PerMessageIV {
+		if reusesKeystream(ctx.cipherMode) && ctx.ivUsed.Swap(true) {
+			return nil, nil, ErrIVReuse
+		}
+		return ctx, nil, nil
+	}
+
+	iv, err := modes.GenerateIV(len(ctx.iv))
+	if err != nil {
+		return nil, nil, fmt.Errorf("IV generation failed: %w", err)
+	}
+	msg, err := ctx.withIV(iv)
+	if err != nil {
+		return nil, nil, err
+	}
+	prefix := make([]byte, len(iv), len(iv)+ctx.cipher.BlockSize())
+	copy(prefix, iv)
+	return msg, prefix, nil
+}
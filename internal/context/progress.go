@@ -0,0 +1,37 @@
+package context
+
+import (
+	stdcontext "context"
+	"io"
+)
+
+// ProgressFunc получает число обработанных байт входа и общий размер (-1, если неизвестен)
+type ProgressFunc func(processed, total int64)
+
+// progressReader прерывает чтение при отмене контекста и сообщает о прогрессе;
+// режимы читают вход фрагментами, так что отмена срабатывает не позже чем через фрагмент
+type progressReader struct {
+	ctx       stdcontext.Context
+	src       io.Reader
+	processed int64
+	total     int64
+	progress  ProgressFunc
+}
+
+func newProgressReader(ctx stdcontext.Context, src io.Reader, total int64, progress ProgressFunc) *progressReader {
+	return &progressReader{ctx: ctx, src: src, total: total, progress: progress}
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	n, err := r.src.Read(p)
+	if n > 0 {
+		r.processed += int64(n)
+		if r.progress != nil {
+			r.progress(r.processed, r.total)
+		}
+	}
+	return n, err
+}
@@ -1,6 +1,7 @@
 package deal
 
 import (
+	"encoding/binary"
 	"fmt"
 	"sync"
 
@@ -15,12 +16,13 @@ const (
 // в стандарте написано что "Let K0 = 0x0123456789abcdef (hex notation) is a fixed DES-key"
 var fixedKey = []byte{0x01, 0x23, 0x45, 0x67, 0x89, 0xAB, 0xCD, 0xEF}
 
+// DEALCipher хранит по DES с уже заданным ключом на каждый раунд: расписание ключей
+// считается один раз в SetKey, а шифрование только читает состояние и может идти параллельно
 type DEALCipher struct {
-	keySize   int
-	numRounds int
-	roundKeys [][]byte
-	desCipher interfaces.BlockCipher
-	mu        sync.RWMutex
+	keySize      int
+	numRounds    int
+	roundCiphers []*des.FastDESCipher
+	mu           sync.RWMutex
 }
 
 func NewDEALCipher(keySize int) (interfaces.BlockCipher, error) {
@@ -33,12 +35,9 @@ func NewDEALCipher(keySize int) (interfaces.BlockCipher, error) {
 		numRounds = 8
 	}
 
-	desCipher := des.NewDESCipher()
-
 	return &DEALCipher{
 		keySize:   keySize,
 		numRounds: numRounds,
-		desCipher: desCipher,
 	}, nil
 }
 
@@ -47,7 +46,8 @@ func (d *DEALCipher) generateRoundKeys(key []byte) ([][]byte, error) {
 		return nil, fmt.Errorf("key must be %d bytes, got %d", d.keySize, len(key))
 	}
 
-	keyGenDES := des.NewDESCipher()
+	// K0 задан стандартом с битами четности, строгий DES его принимает
+	keyGenDES := des.NewStrictDESCipher()
 	if err := keyGenDES.SetKey(fixedKey); err != nil {
 		return nil, err
 	}
@@ -83,6 +83,11 @@ func (d *DEALCipher) generateRoundKeys(key []byte) ([][]byte, error) {
 			return nil, err
 		}
 
+		// раундовый ключ - ключ DES для раундовой функции, слабый сделал бы раунд инволюцией
+		if err := des.CheckWeakKey(encrypted); err != nil {
+			return nil, fmt.Errorf("DEAL round key %d: %w", round, err)
+		}
+
 		roundKeys[round] = encrypted
 		prevRoundKey = encrypted
 	}
@@ -110,95 +115,71 @@ func (d *DEALCipher) SetKey(key []byte) error {
 		return err
 	}
 
+	roundCiphers := make([]*des.FastDESCipher, len(roundKeys))
+	for round, roundKey := range roundKeys {
+		roundCiphers[round] = &des.FastDESCipher{}
+		if err := roundCiphers[round].SetKey(roundKey); err != nil {
+			return err
+		}
+	}
+
 	d.mu.Lock()
-	d.roundKeys = roundKeys
+	d.roundCiphers = roundCiphers
 	d.mu.Unlock()
 
 	return nil
 }
 
 func (d *DEALCipher) EncryptBlock(plaintext []byte) ([]byte, error) {
-	d.mu.Lock()
-	defer d.mu.Unlock()
+	d.mu.RLock()
+	roundCiphers := d.roundCiphers
+	d.mu.RUnlock()
 
 	if len(plaintext) != DEALBlockSize {
 		return nil, fmt.Errorf("block size must be %d bytes, got %d", DEALBlockSize, len(plaintext))
 	}
 
-	if d.roundKeys == nil {
+	if roundCiphers == nil {
 		return nil, fmt.Errorf("key not set")
 	}
 
-	left := make([]byte, 8)
-	right := make([]byte, 8)
-	copy(left, plaintext[0:8])
-	copy(right, plaintext[8:16])
+	left := binary.BigEndian.Uint64(plaintext[0:8])
+	right := binary.BigEndian.Uint64(plaintext[8:16])
 
 	for round := 0; round < d.numRounds; round++ {
-		if err := d.desCipher.SetKey(d.roundKeys[round]); err != nil {
-			return nil, err
-		}
-
-		fOutput, err := d.desCipher.EncryptBlock(left)
-		if err != nil {
-			return nil, err
-		}
-
-		newRight := make([]byte, 8)
-		for i := 0; i < 8; i++ {
-			newRight[i] = fOutput[i] ^ right[i]
-		}
-
-		left, right = newRight, left
+		left, right = roundCiphers[round].Encrypt64(left)^right, left
 	}
 
 	ciphertext := make([]byte, DEALBlockSize)
-	copy(ciphertext[0:8], left)
-	copy(ciphertext[8:16], right)
+	binary.BigEndian.PutUint64(ciphertext[0:8], left)
+	binary.BigEndian.PutUint64(ciphertext[8:16], right)
 
 	return ciphertext, nil
 }
 
 func (d *DEALCipher) DecryptBlock(ciphertext []byte) ([]byte, error) {
-	d.mu.Lock()
-	defer d.mu.Unlock()
+	d.mu.RLock()
+	roundCiphers := d.roundCiphers
+	d.mu.RUnlock()
 
 	if len(ciphertext) != DEALBlockSize {
 		return nil, fmt.Errorf("block size must be %d bytes, got %d", DEALBlockSize, len(ciphertext))
 	}
 
-	if d.roundKeys == nil {
+	if roundCiphers == nil {
 		return nil, fmt.Errorf("key not set")
 	}
 
-	left := make([]byte, 8)
-	right := make([]byte, 8)
-	copy(left, ciphertext[0:8])
-	copy(right, ciphertext[8:16])
+	left := binary.BigEndian.Uint64(ciphertext[0:8])
+	right := binary.BigEndian.Uint64(ciphertext[8:16])
 
 	for round := d.numRounds - 1; round >= 0; round-- {
-		left, right = right, left
-
-		if err := d.desCipher.SetKey(d.roundKeys[round]); err != nil {
-			return nil, err
-		}
-
-		fOutput, err := d.desCipher.EncryptBlock(left)
-		if err != nil {
-			return nil, err
-		}
-
-		newRight := make([]byte, 8)
-		for i := 0; i < 8; i++ {
-			newRight[i] = fOutput[i] ^ right[i]
-		}
-
-		right = newRight
+		left, right = right, roundCiphers[round].Encrypt64(right)^left
 	}
 
 	plaintext := make([]byte, DEALBlockSize)
-	copy(plaintext[0:8], left)
-	copy(plaintext[8:16], right)
+	binary.BigEndian.PutUint64(plaintext[0:8], left)
+	binary.BigEndian.PutUint64(plaintext[8:16], right)
 
 	return plaintext, nil
 }
@@ -206,3 +187,7 @@ func (d *DEALCipher) DecryptBlock(ciphertext []byte) ([]byte, error) {
 func (d *DEALCipher) BlockSize() int {
 	return DEALBlockSize
 }
+
+func (d *DEALCipher) Identify() (interfaces.CipherID, byte) {
+	return interfaces.CipherDEAL, 0
+}
@@ -24,6 +24,7 @@ func TestDEAL_AllKeySizesAndModes(t *testing.T) {
 		{modes.CFB, "CFB"},
 		{modes.OFB, "OFB"},
 		{modes.CTR, "CTR"},
+		{modes.RandomDelta, "RandomDelta"},
 	}
 
 	plaintextSizes := []int{15, 16, 32, 100, 2048}
@@ -105,3 +106,28 @@ func TestDEAL_AllKeySizesAndModes(t *testing.T) {
 		})
 	}
 }
+
+func BenchmarkDEAL(b *testing.B) {
+	block := make([]byte, DEALBlockSize)
+	data := make([]byte, 64*1024)
+	for _, keySize := range []int{16, 24, 32} {
+		c, _ := NewDEALCipher(keySize)
+		key := make([]byte, keySize)
+		rand.Read(key)
+		if err := c.SetKey(key); err != nil {
+			b.Fatalf("SetKey failed: %v", err)
+		}
+		b.Run(fmt.Sprintf("DEAL-%d/EncryptBlock", keySize*8), func(b *testing.B) {
+			b.SetBytes(DEALBlockSize)
+			for i := 0; i < b.N; i++ {
+				c.EncryptBlock(block)
+			}
+		})
+		b.Run(fmt.Sprintf("DEAL-%d/ECB", keySize*8), func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+			for i := 0; i < b.N; i++ {
+				(&modes.ECBMode{}).Encrypt(c, data)
+			}
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package des
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/Qwental/crypota/internal/interfaces"
+)
+
+//go:generate go run gen_sbox.go
+
+// BitsliceLanes - сколько блоков (или ключей) bitsliced DES обрабатывает за один проход
+const BitsliceLanes = 64
+
+// Bitsliced DES: 64 блока транспонируются так, что slices[k] хранит k-й бит (с единицы от
+// старшего, как в таблицах стандарта, но с нуля) всех 64 блоков, по биту на дорожку.
+// Перестановки IP, FP, E и P становятся перенумерацией срезов, а S-блоки считаются
+// схемами из вентилей AND/OR/NOT сразу для всех дорожек (sbox_circuits.go)
+type bitslices [64]uint64
+
+// roundKeySlices - 48 срезов раундового ключа на каждый из 16 раундов
+type roundKeySlices [DESNumRounds][48]uint64
+
+// keyBitSource[r][t] - какой бит исходного ключа попадает в бит t раундового ключа r
+var keyBitSource [DESNumRounds][48]int
+
+func init() {
+	shift := 0
+	for round := range keyBitSource {
+		shift += LeftShifts[round]
+		for t, src := range PC2 {
+			p := src - 1
+			if p < 28 {
+				p = (p + shift) % 28
+			} else {
+				p = 28 + (p-28+shift)%28
+			}
+			keyBitSource[round][t] = PC1[p] - 1
+		}
+	}
+}
+
+// transpose переводит 64 значения в 64 среза: бит (63-k) значения j становится битом j среза k
+func transpose(values *[64]uint64) bitslices {
+	a := *values
+	transpose64(&a)
+	var s bitslices
+	for k := range s {
+		s[k] = a[63-k]
+	}
+	return s
+}
+
+func untranspose(s *bitslices) [64]uint64 {
+	var a [64]uint64
+	for k := range a {
+		a[k] = s[63-k]
+	}
+	transpose64(&a)
+	return a
+}
+
+// transpose64 транспонирует битовую матрицу 64x64 (бит c строки i - элемент (i, c)) на месте:
+// на каждом шаге меняются местами внедиагональные блоки размера j
+func transpose64(a *[64]uint64) {
+	m := uint64(0x00000000FFFFFFFF)
+	for j := 32; j != 0; j, m = j>>1, m^(m<<(j>>1)) {
+		for k := 0; k < 64; k = (k + j + 1) &^ j {
+			t := (a[k]>>j ^ a[k+j]) & m
+			a[k+j] ^= t
+			a[k] ^= t << j
+		}
+	}
+}
+
+// expandKeySlices строит срезы раундовых ключей из срезов 64-битного ключа
+func expandKeySlices(key *bitslices) *roundKeySlices {
+	var rk roundKeySlices
+	for round := range rk {
+		for t := range rk[round] {
+			rk[round][t] = key[keyBitSource[round][t]]
+		}
+	}
+	return &rk
+}
+
+func bitsliceCrypt(rk *roundKeySlices, in *bitslices, decrypt bool) bitslices {
+	var left, right [32]uint64
+	for k := 0; k < 32; k++ {
+		left[k] = in[IP[k]-1]
+		right[k] = in[IP[k+32]-1]
+	}
+
+	var x [48]uint64
+	var sOut [32]uint64
+	for round := 0; round < DESNumRounds; round++ {
+		keys := &rk[round]
+		if decrypt {
+			keys = &rk[DESNumRounds-1-round]
+		}
+		for t := range x {
+			x[t] = right[E[t]-1] ^ keys[t]
+		}
+		applySBoxes(&x, &sOut)
+		for t := range left {
+			left[t] ^= sOut[P[t]-1]
+		}
+		left, right = right, left
+	}
+
+	// после 16 раундов половины идут в FP в порядке R16 || L16
+	var out bitslices
+	for k := range out {
+		src := FP[k] - 1
+		if src < 32 {
+			out[k] = right[src]
+		} else {
+			out[k] = left[src-32]
+		}
+	}
+	return out
+}
+
+// bitsliceMinBlocks - с меньшим числом блоков проход на 64 дорожки медленнее табличного DES
+const bitsliceMinBlocks = 16
+
+var _ interfaces.BatchCipher = (*FastDESCipher)(nil)
+
+// singleKeySlices разворачивает один ключ на все дорожки: каждый срез - 0 или все единицы
+func singleKeySlices(key uint64) *roundKeySlices {
+	var slices bitslices
+	for i := range slices {
+		if key>>(63-i)&1 == 1 {
+			slices[i] = ^uint64(0)
+		}
+	}
+	return expandKeySlices(&slices)
+}
+
+// EncryptBlocks шифрует подряд идущие блоки bitsliced DES по 64 за проход;
+// короткий хвост (меньше bitsliceMinBlocks блоков) считается по таблицам
+func (d *FastDESCipher) EncryptBlocks(dst, src []byte) error {
+	return d.processBlocks(dst, src, false)
+}
+
+func (d *FastDESCipher) DecryptBlocks(dst, src []byte) error {
+	return d.processBlocks(dst, src, true)
+}
+
+func (d *FastDESCipher) processBlocks(dst, src []byte, decrypt bool) error {
+	if len(src)%DESBlockSize != 0 {
+		return fmt.Errorf("input length must be multiple of %d bytes, got %d", DESBlockSize, len(src))
+	}
+	if len(dst) < len(src) {
+		return fmt.Errorf("output buffer too short: %d < %d", len(dst), len(src))
+	}
+	if !d.keySet {
+		return fmt.Errorf("round keys not initialized")
+	}
+
+	const batch = BitsliceLanes * DESBlockSize
+	for offset := 0; offset < len(src); offset += batch {
+		end := min(offset+batch, len(src))
+		if (end-offset)/DESBlockSize < bitsliceMinBlocks {
+			for j := offset; j < end; j += DESBlockSize {
+				binary.BigEndian.PutUint64(dst[j:], cryptBlock(&d.subkeys, binary.BigEndian.Uint64(src[j:]), decrypt))
+			}
+			continue
+		}
+
+		var blocks [64]uint64
+		for j := 0; offset+j*DESBlockSize < end; j++ {
+			blocks[j] = binary.BigEndian.Uint64(src[offset+j*DESBlockSize:])
+		}
+		slices := transpose(&blocks)
+		slices = bitsliceCrypt(d.keySlices, &slices, decrypt)
+		blocks = untranspose(&slices)
+		for j := 0; offset+j*DESBlockSize < end; j++ {
+			binary.BigEndian.PutUint64(dst[offset+j*DESBlockSize:], blocks[j])
+		}
+	}
+	return nil
+}
+
+// EncryptWithKeys шифрует один блок на каждом из ключей, по 64 ключа за проход;
+// для перебора ключей. Ключи и блоки - 64-битные big-endian значения
+func EncryptWithKeys(keys []uint64, plaintext uint64) []uint64 {
+	var block bitslices
+	for k := range block {
+		if plaintext>>(63-k)&1 == 1 {
+			block[k] = ^uint64(0)
+		}
+	}
+
+	out := make([]uint64, len(keys))
+	for offset := 0; offset < len(keys); offset += BitsliceLanes {
+		var lanes [64]uint64
+		n := copy(lanes[:], keys[offset:])
+		keySlices := transpose(&lanes)
+		slices := bitsliceCrypt(expandKeySlices(&keySlices), &block, false)
+		lanes = untranspose(&slices)
+		copy(out[offset:offset+n], lanes[:n])
+	}
+	return out
+}
@@ -0,0 +1,163 @@
+package des
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"testing"
+)
+
+var bitsliceKnownAnswers = []struct {
+	key, plaintext, ciphertext string
+}{
+	{"133457799BBCDFF1", "0123456789ABCDEF", "85E813540F0AB405"},
+	{"0101010101010101", "0000000000000000", "8CA64DE9C1B123A7"},
+	{"FFFFFFFFFFFFFFFF", "FFFFFFFFFFFFFFFF", "7359B2163E4EDC58"},
+	{"0E329232EA6D0D73", "8787878787878787", "0000000000000000"},
+}
+
+func TestBitslicedDESKnownAnswer(t *testing.T) {
+	for _, tt := range bitsliceKnownAnswers {
+		key, _ := hex.DecodeString(tt.key)
+		plaintext, _ := hex.DecodeString(tt.plaintext)
+		expected, _ := hex.DecodeString(tt.ciphertext)
+
+		cipher := NewFastDESCipher().(*FastDESCipher)
+		if err := cipher.SetKey(key); err != nil {
+			t.Fatalf("SetKey failed: %v", err)
+		}
+
+		// блок повторяется на всех 64 дорожках и на части дорожек следующего прохода
+		src := bytes.Repeat(plaintext, BitsliceLanes+bitsliceMinBlocks)
+		dst := make([]byte, len(src))
+		if err := cipher.EncryptBlocks(dst, src); err != nil {
+			t.Fatalf("EncryptBlocks failed: %v", err)
+		}
+		if !bytes.Equal(dst, bytes.Repeat(expected, BitsliceLanes+bitsliceMinBlocks)) {
+			t.Errorf("key %s: EncryptBlocks does not match %s", tt.key, tt.ciphertext)
+		}
+		if err := cipher.DecryptBlocks(dst, dst); err != nil {
+			t.Fatalf("DecryptBlocks failed: %v", err)
+		}
+		if !bytes.Equal(dst, src) {
+			t.Errorf("key %s: DecryptBlocks round-trip failed", tt.key)
+		}
+
+		out := EncryptWithKeys([]uint64{binary.BigEndian.Uint64(key)}, binary.BigEndian.Uint64(plaintext))
+		if out[0] != binary.BigEndian.Uint64(expected) {
+			t.Errorf("key %s: EncryptWithKeys = %016X, want %s", tt.key, out[0], tt.ciphertext)
+		}
+	}
+}
+
+func TestBitslicedDESMatchesReference(t *testing.T) {
+	key := make([]byte, 8)
+	rand.Read(key)
+	reference := NewDESCipher()
+	reference.SetKey(key)
+	cipher := NewFastDESCipher().(*FastDESCipher)
+	cipher.SetKey(key)
+
+	// короткие пакеты и хвосты идут по таблицам, остальные - через bitsliced DES
+	for _, blocks := range []int{1, bitsliceMinBlocks - 1, bitsliceMinBlocks, 63, 64, 65, 64 + bitsliceMinBlocks, 200} {
+		src := make([]byte, blocks*DESBlockSize)
+		rand.Read(src)
+		dst := make([]byte, len(src))
+		if err := cipher.EncryptBlocks(dst, src); err != nil {
+			t.Fatalf("EncryptBlocks failed: %v", err)
+		}
+		for i := 0; i < len(src); i += DESBlockSize {
+			expected, _ := reference.EncryptBlock(src[i : i+DESBlockSize])
+			if !bytes.Equal(dst[i:i+DESBlockSize], expected) {
+				t.Fatalf("%d blocks: block %d differs from reference DES", blocks, i/DESBlockSize)
+			}
+		}
+	}
+
+	if err := cipher.EncryptBlocks(make([]byte, 8), make([]byte, 12)); err == nil {
+		t.Error("Expected error for input not aligned to blocks")
+	}
+	if err := cipher.EncryptBlocks(make([]byte, 8), make([]byte, 16)); err == nil {
+		t.Error("Expected error for short output buffer")
+	}
+	if err := NewFastDESCipher().(*FastDESCipher).EncryptBlocks(make([]byte, 8), make([]byte, 8)); err == nil {
+		t.Error("Expected error before SetKey")
+	}
+}
+
+// поиск ключа перебором: среди 100 кандидатов один правильный
+func TestEncryptWithKeysFindsKey(t *testing.T) {
+	keys := make([]uint64, 100)
+	for i := range keys {
+		var b [8]byte
+		rand.Read(b[:])
+		keys[i] = binary.BigEndian.Uint64(b[:])
+	}
+	secret := keys[77]
+	plaintext := uint64(0x0123456789ABCDEF)
+
+	reference := NewDESCipher()
+	var keyBytes, block [8]byte
+	binary.BigEndian.PutUint64(keyBytes[:], secret)
+	binary.BigEndian.PutUint64(block[:], plaintext)
+	reference.SetKey(keyBytes[:])
+	target, _ := reference.EncryptBlock(block[:])
+
+	found := -1
+	for i, c := range EncryptWithKeys(keys, plaintext) {
+		binary.BigEndian.PutUint64(block[:], c)
+		if bytes.Equal(block[:], target) {
+			found = i
+		}
+	}
+	if found != 77 {
+		t.Errorf("Key search found candidate %d, want 77", found)
+	}
+}
+
+func BenchmarkBitslicedDES(b *testing.B) {
+	key := []byte{0x13, 0x34, 0x57, 0x79, 0x9B, 0xBC, 0xDF, 0xF1}
+	data := make([]byte, 64*1024)
+	dst := make([]byte, len(data))
+
+	fast := NewFastDESCipher()
+	fast.SetKey(key)
+	b.Run("Fast", func(b *testing.B) {
+		b.SetBytes(int64(len(data)))
+		for i := 0; i < b.N; i++ {
+			for j := 0; j < len(data); j += DESBlockSize {
+				out, _ := fast.EncryptBlock(data[j : j+DESBlockSize])
+				copy(dst[j:], out)
+			}
+		}
+	})
+
+	batch := fast.(*FastDESCipher)
+	b.Run("Bitsliced", func(b *testing.B) {
+		b.SetBytes(int64(len(data)))
+		for i := 0; i < b.N; i++ {
+			batch.EncryptBlocks(dst, data)
+		}
+	})
+}
+
+func TestTransposeMatchesDefinition(t *testing.T) {
+	var values [64]uint64
+	for j := range values {
+		var b [8]byte
+		rand.Read(b[:])
+		values[j] = binary.BigEndian.Uint64(b[:])
+	}
+	s := transpose(&values)
+	for k := range s {
+		for j := range values {
+			if s[k]>>j&1 != values[j]>>(63-k)&1 {
+				t.Fatalf("slice %d lane %d does not match bit %d of value %d", k, j, 63-k, j)
+			}
+		}
+	}
+	if untranspose(&s) != values {
+		t.Error("untranspose is not the inverse of transpose")
+	}
+}
@@ -2,7 +2,6 @@ package des
 
 import (
 	"fmt"
-	"github.com/Qwental/crypota/internal/bitops"
 	"github.com/Qwental/crypota/internal/feistel"
 	"github.com/Qwental/crypota/internal/interfaces"
 )
@@ -14,6 +13,7 @@ const (
 
 type DESCipher struct {
 	feistel *feistel.FeistelCipher
+	strict  bool
 }
 
 func NewDESCipher() interfaces.BlockCipher {
@@ -32,10 +32,23 @@ func NewDESCipher() interfaces.BlockCipher {
 	}
 }
 
+// NewStrictDESCipher - DES, у которого SetKey отвергает ключи с неверной четностью,
+// слабые и полуслабые ключи (см. CheckKey)
+func NewStrictDESCipher() interfaces.BlockCipher {
+	d := NewDESCipher().(*DESCipher)
+	d.strict = true
+	return d
+}
+
 func (d *DESCipher) SetKey(key []byte) error {
 	if len(key) != 8 {
 		return fmt.Errorf("DES key must be 8 bytes, got %d", len(key))
 	}
+	if d.strict {
+		if err := CheckKey(key); err != nil {
+			return err
+		}
+	}
 	return d.feistel.SetKey(key)
 }
 
@@ -44,14 +57,9 @@ func (d *DESCipher) EncryptBlock(plaintext []byte) ([]byte, error) {
 		return nil, fmt.Errorf("block size must be %d bytes, got %d", DESBlockSize, len(plaintext))
 	}
 
-	config := bitops.PermuteConfig{
-		Indexing:  bitops.MSBFirst,
-		Numbering: bitops.OneBased,
-	}
-
 	// IP
-	permutedBlock, err := bitops.Permute(plaintext, IP, config)
-	if err != nil {
+	permutedBlock := make([]byte, DESBlockSize)
+	if err := ipPermutation.Apply(permutedBlock, plaintext); err != nil {
 		return nil, fmt.Errorf("initial permutation failed: %w", err)
 	}
 
@@ -62,8 +70,8 @@ func (d *DESCipher) EncryptBlock(plaintext []byte) ([]byte, error) {
 	}
 
 	// FP
-	ciphertext, err := bitops.Permute(feistelOutput, FP, config)
-	if err != nil {
+	ciphertext := make([]byte, DESBlockSize)
+	if err := fpPermutation.Apply(ciphertext, feistelOutput); err != nil {
 		return nil, fmt.Errorf("final permutation failed: %w", err)
 	}
 
@@ -75,14 +83,9 @@ func (d *DESCipher) DecryptBlock(ciphertext []byte) ([]byte, error) {
 		return nil, fmt.Errorf("block size must be %d bytes, got %d", DESBlockSize, len(ciphertext))
 	}
 
-	config := bitops.PermuteConfig{
-		Indexing:  bitops.MSBFirst,
-		Numbering: bitops.OneBased,
-	}
-
-	//  IP
-	permutedBlock, err := bitops.Permute(ciphertext, IP, config)
-	if err != nil {
+	// IP
+	permutedBlock := make([]byte, DESBlockSize)
+	if err := ipPermutation.Apply(permutedBlock, ciphertext); err != nil {
 		return nil, fmt.Errorf("initial permutation failed: %w", err)
 	}
 
@@ -92,9 +95,9 @@ func (d *DESCipher) DecryptBlock(ciphertext []byte) ([]byte, error) {
 		return nil, fmt.Errorf("feistel decryption failed: %w", err)
 	}
 
-	//  FP
-	plaintext, err := bitops.Permute(feistelOutput, FP, config)
-	if err != nil {
+	// FP
+	plaintext := make([]byte, DESBlockSize)
+	if err := fpPermutation.Apply(plaintext, feistelOutput); err != nil {
 		return nil, fmt.Errorf("final permutation failed: %w", err)
 	}
 
@@ -105,3 +108,7 @@ func (d *DESCipher) BlockSize() int {
 	return DESBlockSize
 }
 
+
+func (d *DESCipher) Identify() (interfaces.CipherID, byte) {
+	return interfaces.CipherDES, 0
+}
@@ -4,7 +4,10 @@ import (
 	"bytes"
 	"crypto/des"
 	"encoding/hex"
+	"slices"
 	"testing"
+
+	"github.com/Qwental/crypota/internal/bitops"
 )
 
 
@@ -354,3 +357,30 @@ func TestDESInvalidBlockSize(t *testing.T) {
 	}
 }
 
+
+func TestCompiledTablesIPFPInverse(t *testing.T) {
+	inverse, err := bitops.InversePBlock(IP, bitops.OneBased)
+	if err != nil {
+		t.Fatalf("IP is not bijective: %v", err)
+	}
+	if !slices.Equal(inverse, FP) {
+		t.Fatal("inverse of IP does not match FP")
+	}
+
+	block := []byte{0x01, 0x23, 0x45, 0x67, 0x89, 0xAB, 0xCD, 0xEF}
+	permuted := make([]byte, DESBlockSize)
+	restored := make([]byte, DESBlockSize)
+	if err := ipPermutation.Apply(permuted, block); err != nil {
+		t.Fatal(err)
+	}
+	fp, err := ipPermutation.Inverse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fp.Apply(restored, permuted); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(restored, block) {
+		t.Fatalf("IP^-1(IP(x)) = %x, want %x", restored, block)
+	}
+}
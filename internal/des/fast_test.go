@@ -0,0 +1,96 @@
+package des
+
+import (
+	"bytes"
+	"crypto/des"
+	"crypto/rand"
+	"testing"
+
+	"github.com/Qwental/crypota/internal/interfaces"
+)
+
+func TestFastDESMatchesFeistelDES(t *testing.T) {
+	reference := NewDESCipher()
+	fast := NewFastDESCipher()
+
+	for i := 0; i < 200; i++ {
+		key := make([]byte, 8)
+		block := make([]byte, 8)
+		rand.Read(key)
+		rand.Read(block)
+
+		if err := reference.SetKey(key); err != nil {
+			t.Fatalf("SetKey failed: %v", err)
+		}
+		if err := fast.SetKey(key); err != nil {
+			t.Fatalf("SetKey failed: %v", err)
+		}
+
+		expected, _ := reference.EncryptBlock(block)
+		ciphertext, err := fast.EncryptBlock(block)
+		if err != nil {
+			t.Fatalf("EncryptBlock failed: %v", err)
+		}
+		if !bytes.Equal(ciphertext, expected) {
+			t.Fatalf("key %x block %x: fast %x, Feistel %x", key, block, ciphertext, expected)
+		}
+
+		expected, _ = reference.DecryptBlock(block)
+		decrypted, err := fast.DecryptBlock(block)
+		if err != nil {
+			t.Fatalf("DecryptBlock failed: %v", err)
+		}
+		if !bytes.Equal(decrypted, expected) {
+			t.Fatalf("key %x block %x: fast decrypt %x, Feistel %x", key, block, decrypted, expected)
+		}
+	}
+}
+
+func TestFastDESAgainstStandardLibrary(t *testing.T) {
+	key := []byte{0x13, 0x34, 0x57, 0x79, 0x9B, 0xBC, 0xDF, 0xF1}
+	plaintext := []byte{0x01, 0x23, 0x45, 0x67, 0x89, 0xAB, 0xCD, 0xEF}
+
+	fast := NewFastDESCipher()
+	if _, err := fast.EncryptBlock(plaintext); err == nil {
+		t.Error("Expected error before SetKey")
+	}
+	fast.SetKey(key)
+	ciphertext, _ := fast.EncryptBlock(plaintext)
+
+	stdBlock, _ := des.NewCipher(key)
+	expected := make([]byte, 8)
+	stdBlock.Encrypt(expected, plaintext)
+	if !bytes.Equal(ciphertext, expected) {
+		t.Errorf("Ciphertext %x, want %x", ciphertext, expected)
+	}
+
+	if _, err := fast.EncryptBlock(plaintext[:7]); err == nil {
+		t.Error("Expected error for 7-byte block")
+	}
+	if err := fast.SetKey(key[:7]); err == nil {
+		t.Error("Expected error for 7-byte key")
+	}
+}
+
+func BenchmarkDESCores(b *testing.B) {
+	key := []byte{0x13, 0x34, 0x57, 0x79, 0x9B, 0xBC, 0xDF, 0xF1}
+	block := make([]byte, 8)
+	for name, newCipher := range map[string]func() interfaces.BlockCipher{
+		"Feistel": NewDESCipher,
+		"Fast":    NewFastDESCipher,
+	} {
+		c := newCipher()
+		c.SetKey(key)
+		b.Run(name+"/EncryptBlock", func(b *testing.B) {
+			b.SetBytes(8)
+			for i := 0; i < b.N; i++ {
+				c.EncryptBlock(block)
+			}
+		})
+		b.Run(name+"/SetKey", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				c.SetKey(key)
+			}
+		})
+	}
+}
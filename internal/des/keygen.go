@@ -3,7 +3,6 @@ package des
 import (
 	"encoding/binary"
 	"fmt"
-	"github.com/Qwental/crypota/internal/bitops"
 )
 
 type DESKeyScheduler struct{}
@@ -22,14 +21,9 @@ func (ks *DESKeyScheduler) GenerateRoundKeys(key []byte) ([][]byte, error) {
 		return nil, fmt.Errorf("DES key must be 8 bytes, got %d", len(key))
 	}
 
-	config := bitops.PermuteConfig{
-		Indexing:  bitops.MSBFirst,
-		Numbering: bitops.OneBased,
-	}
-
 	// PC-1 64 в 56 бит
-	permutedKeyBytes, err := bitops.Permute(key, PC1, config)
-	if err != nil {
+	permutedKeyBytes := make([]byte, 7)
+	if err := pc1Permutation.Apply(permutedKeyBytes, key); err != nil {
 		return nil, fmt.Errorf("PC1 permutation failed: %w", err)
 	}
 
@@ -55,8 +49,8 @@ func (ks *DESKeyScheduler) GenerateRoundKeys(key []byte) ([][]byte, error) {
 		copy(cdBytes, paddedBytes[1:])
 
 		// PC-2 56 в  48 бит
-		roundKey, err := bitops.Permute(cdBytes, PC2, config)
-		if err != nil {
+		roundKey := make([]byte, 6)
+		if err := pc2Permutation.Apply(roundKey, cdBytes); err != nil {
 			return nil, fmt.Errorf("PC2 permutation failed in round %d: %w", round, err)
 		}
 
@@ -0,0 +1,143 @@
+package des
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrParity - в каком-то байте ключа четное число единиц
+	ErrParity = errors.New("DES key has wrong parity")
+	// ErrWeakKey - все 16 раундовых ключей одинаковы, шифрование совпадает с расшифрованием
+	ErrWeakKey = errors.New("DES key is weak")
+	// ErrSemiWeakKey - ключ из полуслабой пары: шифрование на одном ключе пары расшифровывает другим
+	ErrSemiWeakKey = errors.New("DES key is semi-weak")
+)
+
+// слабые и полуслабые ключи (FIPS 74), записаны с битами четности
+var weakKeys = [][]byte{
+	{0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01},
+	{0xFE, 0xFE, 0xFE, 0xFE, 0xFE, 0xFE, 0xFE, 0xFE},
+	{0xE0, 0xE0, 0xE0, 0xE0, 0xF1, 0xF1, 0xF1, 0xF1},
+	{0x1F, 0x1F, 0x1F, 0x1F, 0x0E, 0x0E, 0x0E, 0x0E},
+}
+
+var semiWeakKeys = [][]byte{
+	{0x01, 0x1F, 0x01, 0x1F, 0x01, 0x0E, 0x01, 0x0E}, {0x1F, 0x01, 0x1F, 0x01, 0x0E, 0x01, 0x0E, 0x01},
+	{0x01, 0xE0, 0x01, 0xE0, 0x01, 0xF1, 0x01, 0xF1}, {0xE0, 0x01, 0xE0, 0x01, 0xF1, 0x01, 0xF1, 0x01},
+	{0x01, 0xFE, 0x01, 0xFE, 0x01, 0xFE, 0x01, 0xFE}, {0xFE, 0x01, 0xFE, 0x01, 0xFE, 0x01, 0xFE, 0x01},
+	{0x1F, 0xE0, 0x1F, 0xE0, 0x0E, 0xF1, 0x0E, 0xF1}, {0xE0, 0x1F, 0xE0, 0x1F, 0xF1, 0x0E, 0xF1, 0x0E},
+	{0x1F, 0xFE, 0x1F, 0xFE, 0x0E, 0xFE, 0x0E, 0xFE}, {0xFE, 0x1F, 0xFE, 0x1F, 0xFE, 0x0E, 0xFE, 0x0E},
+	{0xE0, 0xFE, 0xE0, 0xFE, 0xF1, 0xFE, 0xF1, 0xFE}, {0xFE, 0xE0, 0xFE, 0xE0, 0xFE, 0xF1, 0xFE, 0xF1},
+}
+
+// SetParity возвращает копию ключа, в которой младший бит каждого байта дополняет его до нечетности
+func SetParity(key []byte) []byte {
+	out := make([]byte, len(key))
+	for i, b := range key {
+		out[i] = b&0xFE | (^parity(b>>1) & 1)
+	}
+	return out
+}
+
+// HasOddParity проверяет, что в каждом байте ключа нечетное число единиц
+func HasOddParity(key []byte) bool {
+	for _, b := range key {
+		if parity(b) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func parity(b byte) byte {
+	b ^= b >> 4
+	b ^= b >> 2
+	b ^= b >> 1
+	return b & 1
+}
+
+// биты четности в расписание ключей не попадают, поэтому ключи сравниваются без них
+func inKeyList(key []byte, list [][]byte) bool {
+	if len(key) != 8 {
+		return false
+	}
+	normalized := SetParity(key)
+	for _, k := range list {
+		if bytes.Equal(normalized, k) {
+			return true
+		}
+	}
+	return false
+}
+
+func IsWeakKey(key []byte) bool {
+	return inKeyList(key, weakKeys)
+}
+
+func IsSemiWeakKey(key []byte) bool {
+	return inKeyList(key, semiWeakKeys)
+}
+
+// половины C и D возможно слабых ключей - повторенный 4-битный шаблон из этого набора
+var possiblyWeakHalves = map[uint32]bool{
+	0x0000000: true, 0xFFFFFFF: true, 0x5555555: true, 0xAAAAAAA: true,
+	0x3333333: true, 0x6666666: true, 0xCCCCCCC: true, 0x9999999: true,
+}
+
+// IsPossiblyWeakKey - один из 48 возможно слабых ключей (Davies): у них всего четыре
+// различных раундовых ключа. Слабые и полуслабые ключи сюда не входят
+func IsPossiblyWeakKey(key []byte) bool {
+	if len(key) != 8 || IsWeakKey(key) || IsSemiWeakKey(key) {
+		return false
+	}
+	permuted := make([]byte, 7)
+	if err := pc1Permutation.Apply(permuted, key); err != nil {
+		return false
+	}
+	var cd uint64
+	for _, b := range permuted {
+		cd = cd<<8 | uint64(b)
+	}
+	return possiblyWeakHalves[uint32(cd>>28)] && possiblyWeakHalves[uint32(cd&0x0FFFFFFF)]
+}
+
+// CheckWeakKey возвращает ErrWeakKey или ErrSemiWeakKey; четность не проверяется,
+// так что подходит и для раундовых ключей DEAL
+func CheckWeakKey(key []byte) error {
+	switch {
+	case IsWeakKey(key):
+		return ErrWeakKey
+	case IsSemiWeakKey(key):
+		return ErrSemiWeakKey
+	}
+	return nil
+}
+
+// CheckKey - проверка строгого режима: длина, нечетная четность, не слабый и не полуслабый
+func CheckKey(key []byte) error {
+	if len(key) != 8 {
+		return fmt.Errorf("DES key must be 8 bytes, got %d", len(key))
+	}
+	if !HasOddParity(key) {
+		return ErrParity
+	}
+	return CheckWeakKey(key)
+}
+
+// GenerateKey возвращает случайный ключ с битами четности, который проходит CheckKey
+// и не является возможно слабым
+func GenerateKey() ([]byte, error) {
+	key := make([]byte, 8)
+	for {
+		if _, err := rand.Read(key); err != nil {
+			return nil, fmt.Errorf("failed to generate DES key: %w", err)
+		}
+		key = SetParity(key)
+		if CheckKey(key) == nil && !IsPossiblyWeakKey(key) {
+			return key, nil
+		}
+	}
+}
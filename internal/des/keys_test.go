@@ -0,0 +1,155 @@
+package des
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestParity(t *testing.T) {
+	key := []byte{0x00, 0x01, 0x02, 0x03, 0xFE, 0xFF, 0x12, 0x34}
+	if HasOddParity(key) {
+		t.Error("Key with even-parity bytes reported as odd parity")
+	}
+
+	fixed := SetParity(key)
+	expected := []byte{0x01, 0x01, 0x02, 0x02, 0xFE, 0xFE, 0x13, 0x34}
+	if !bytes.Equal(fixed, expected) {
+		t.Errorf("SetParity = %x, want %x", fixed, expected)
+	}
+	if !HasOddParity(fixed) {
+		t.Error("SetParity result must have odd parity")
+	}
+	if key[0] != 0x00 {
+		t.Error("SetParity must not modify its argument")
+	}
+}
+
+func TestWeakAndSemiWeakKeys(t *testing.T) {
+	for _, key := range weakKeys {
+		if !IsWeakKey(key) || distinctRoundKeys(key) != 1 {
+			t.Errorf("%x: expected weak key with a single round key", key)
+		}
+		// без битов четности ключ остается слабым
+		stripped := make([]byte, 8)
+		for i := range key {
+			stripped[i] = key[i] & 0xFE
+		}
+		if !errors.Is(CheckWeakKey(stripped), ErrWeakKey) {
+			t.Errorf("%x: weak key without parity bits not detected", stripped)
+		}
+	}
+
+	for i := 0; i < len(semiWeakKeys); i += 2 {
+		k1, k2 := semiWeakKeys[i], semiWeakKeys[i+1]
+		if !IsSemiWeakKey(k1) || !IsSemiWeakKey(k2) || distinctRoundKeys(k1) != 2 {
+			t.Errorf("%x: expected semi-weak key with two round keys", k1)
+		}
+
+		// шифрование на одном ключе пары снимается шифрованием на другом
+		c1, c2 := NewDESCipher(), NewDESCipher()
+		c1.SetKey(k1)
+		c2.SetKey(k2)
+		plaintext := []byte("semiweak")
+		ciphertext, _ := c1.EncryptBlock(plaintext)
+		back, _ := c2.EncryptBlock(ciphertext)
+		if !bytes.Equal(back, plaintext) {
+			t.Errorf("%x/%x: E_k2(E_k1(P)) != P", k1, k2)
+		}
+	}
+
+	if err := CheckWeakKey([]byte{0x13, 0x34, 0x57, 0x79, 0x9B, 0xBC, 0xDF, 0xF1}); err != nil {
+		t.Errorf("Ordinary key reported as weak: %v", err)
+	}
+}
+
+func distinctRoundKeys(key []byte) int {
+	roundKeys, err := NewDESKeyScheduler().GenerateRoundKeys(key)
+	if err != nil {
+		return 0
+	}
+	distinct := make(map[string]struct{})
+	for _, k := range roundKeys {
+		distinct[string(k)] = struct{}{}
+	}
+	return len(distinct)
+}
+
+// keyFromHalves собирает ключ, у которого после PC-1 получаются заданные половины C и D
+func keyFromHalves(c, d uint32) []byte {
+	cd := uint64(c)<<28 | uint64(d)
+	key := make([]byte, 8)
+	for i, src := range PC1 {
+		if cd>>(55-i)&1 == 1 {
+			key[(src-1)/8] |= 0x80 >> ((src - 1) % 8)
+		}
+	}
+	return SetParity(key)
+}
+
+func TestPossiblyWeakKeys(t *testing.T) {
+	// все кандидаты: половины C и D - повторенный 4-битный шаблон
+	counts := map[int]int{}
+	for a := uint32(0); a < 16; a++ {
+		for b := uint32(0); b < 16; b++ {
+			var c, d uint32
+			for i := 0; i < 7; i++ {
+				c = c<<4 | a
+				d = d<<4 | b
+			}
+			key := keyFromHalves(c, d)
+			switch {
+			case IsWeakKey(key):
+				counts[1]++
+			case IsSemiWeakKey(key):
+				counts[2]++
+			case IsPossiblyWeakKey(key):
+				counts[4]++
+				if n := distinctRoundKeys(key); n != 4 {
+					t.Errorf("%x: possibly weak key has %d distinct round keys, want 4", key, n)
+				}
+			}
+		}
+	}
+	if counts[1] != 4 || counts[2] != 12 || counts[4] != 48 {
+		t.Errorf("Found %d weak, %d semi-weak, %d possibly weak keys, want 4, 12, 48", counts[1], counts[2], counts[4])
+	}
+
+	if !IsPossiblyWeakKey([]byte{0x1F, 0x1F, 0x01, 0x01, 0x0E, 0x0E, 0x01, 0x01}) {
+		t.Error("1F1F01010E0E0101 must be possibly weak")
+	}
+}
+
+func TestStrictDESCipher(t *testing.T) {
+	strict := NewStrictDESCipher()
+	tests := []struct {
+		name string
+		key  []byte
+		want error
+	}{
+		{"weak", weakKeys[2], ErrWeakKey},
+		{"semi-weak", semiWeakKeys[5], ErrSemiWeakKey},
+		{"wrong parity", []byte{0x13, 0x34, 0x57, 0x79, 0x9B, 0xBC, 0xDF, 0xF0}, ErrParity},
+	}
+	for _, tt := range tests {
+		if err := strict.SetKey(tt.key); !errors.Is(err, tt.want) {
+			t.Errorf("%s: SetKey error %v, want %v", tt.name, err, tt.want)
+		}
+		if err := NewDESCipher().SetKey(tt.key); err != nil {
+			t.Errorf("%s: non-strict DES must accept any key, got %v", tt.name, err)
+		}
+	}
+	if err := strict.SetKey([]byte{0x13, 0x34, 0x57, 0x79, 0x9B, 0xBC, 0xDF, 0xF1}); err != nil {
+		t.Errorf("Strict DES rejected a good key: %v", err)
+	}
+
+	for i := 0; i < 20; i++ {
+		key, err := GenerateKey()
+		if err != nil {
+			t.Fatalf("GenerateKey failed: %v", err)
+		}
+		if err := strict.SetKey(key); err != nil || IsPossiblyWeakKey(key) {
+			t.Fatalf("Generated key %x does not pass checks: %v", key, err)
+		}
+	}
+}
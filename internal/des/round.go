@@ -2,7 +2,6 @@ package des
 
 import (
 	"fmt"
-	"github.com/Qwental/crypota/internal/bitops"
 )
 
 type DESRoundFunction struct{}
@@ -19,14 +18,9 @@ func (rf *DESRoundFunction) Apply(block []byte, roundKey []byte) ([]byte, error)
 		return nil, fmt.Errorf("DES round key must be 6 bytes (48 bits), got %d", len(roundKey))
 	}
 
-	config := bitops.PermuteConfig{
-		Indexing:  bitops.MSBFirst,
-		Numbering: bitops.OneBased,
-	}
-
 	// E  32 в 48 
-	expanded, err := bitops.Permute(block, E, config)
-	if err != nil {
+	expanded := make([]byte, 6)
+	if err := ePermutation.Apply(expanded, block); err != nil {
 		return nil, fmt.Errorf("expansion failed: %w", err)
 	}
 
@@ -64,8 +58,8 @@ func (rf *DESRoundFunction) Apply(block []byte, roundKey []byte) ([]byte, error)
 	}
 
 
-	result, err := bitops.Permute(sboxOutput, P, config)
-	if err != nil {
+	result := make([]byte, 4)
+	if err := pPermutation.Apply(result, sboxOutput); err != nil {
 		return nil, fmt.Errorf(" P permutation failed: %w", err)
 	}
 
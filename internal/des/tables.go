@@ -1,5 +1,7 @@
 package des
 
+import "github.com/Qwental/crypota/internal/bitops"
+
 // PC1
 var PC1 = []int{
 	57, 49, 41, 33, 25, 17, 9,
@@ -132,3 +134,18 @@ var SBoxes = [8][4][16]byte{
 }
 
 var LeftShifts = []int{1, 1, 2, 2, 2, 2, 2, 2, 1, 2, 2, 2, 2, 2, 2, 1}
+
+// таблицы выше, скомпилированные один раз: биты от старшего, нумерация с единицы, как в стандарте
+var bitConfig = bitops.PermuteConfig{
+	Indexing:  bitops.MSBFirst,
+	Numbering: bitops.OneBased,
+}
+
+var (
+	ipPermutation  = bitops.MustCompile(IP, bitConfig)
+	fpPermutation  = bitops.MustCompile(FP, bitConfig)
+	ePermutation   = bitops.MustCompile(E, bitConfig)
+	pPermutation   = bitops.MustCompile(P, bitConfig)
+	pc1Permutation = bitops.MustCompile(PC1, bitConfig)
+	pc2Permutation = bitops.MustCompile(PC2, bitConfig)
+)
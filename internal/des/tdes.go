@@ -0,0 +1,145 @@
+package des
+
+import (
+	"fmt"
+
+	"github.com/Qwental/crypota/internal/interfaces"
+)
+
+// TripleDESCipher - TDEA (NIST SP 800-67) в варианте EDE: C = E_K3(D_K2(E_K1(P))).
+// Вариант ключа задается его длиной:
+//   - 24 байта - три независимых ключа (keying option 1)
+//   - 16 байт - K1 = K3 (keying option 2, EDE2)
+//   - 8 байт - K1 = K2 = K3, совместим с одинарным DES (keying option 3)
+type TripleDESCipher struct {
+	keySize int
+	stages  [3]interfaces.BlockCipher
+}
+
+func NewTripleDESCipher(keySize int) (interfaces.BlockCipher, error) {
+	if keySize != 8 && keySize != 16 && keySize != 24 {
+		return nil, fmt.Errorf("3DES key size must be 8, 16 or 24 bytes, got %d", keySize)
+	}
+	return &TripleDESCipher{
+		keySize: keySize,
+		stages:  [3]interfaces.BlockCipher{NewFastDESCipher(), NewFastDESCipher(), NewFastDESCipher()},
+	}, nil
+}
+
+func (t *TripleDESCipher) SetKey(key []byte) error {
+	if len(key) != t.keySize {
+		return fmt.Errorf("3DES key must be %d bytes, got %d", t.keySize, len(key))
+	}
+
+	var keys [3][]byte
+	switch t.keySize {
+	case 8:
+		keys = [3][]byte{key, key, key}
+	case 16:
+		keys = [3][]byte{key[:8], key[8:], key[:8]}
+	default:
+		keys = [3][]byte{key[:8], key[8:16], key[16:]}
+	}
+	for i, stage := range t.stages {
+		if err := stage.SetKey(keys[i]); err != nil {
+			return fmt.Errorf("3DES key %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+func (t *TripleDESCipher) EncryptBlock(plaintext []byte) ([]byte, error) {
+	block, err := t.stages[0].EncryptBlock(plaintext)
+	if err != nil {
+		return nil, err
+	}
+	if block, err = t.stages[1].DecryptBlock(block); err != nil {
+		return nil, err
+	}
+	return t.stages[2].EncryptBlock(block)
+}
+
+func (t *TripleDESCipher) DecryptBlock(ciphertext []byte) ([]byte, error) {
+	block, err := t.stages[2].DecryptBlock(ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	if block, err = t.stages[1].EncryptBlock(block); err != nil {
+		return nil, err
+	}
+	return t.stages[0].DecryptBlock(block)
+}
+
+func (t *TripleDESCipher) BlockSize() int {
+	return DESBlockSize
+}
+
+func (t *TripleDESCipher) Identify() (interfaces.CipherID, byte) {
+	return interfaces.CipherTripleDES, 0
+}
+
+// DESXCipher - DES с отбеливанием ключа (Rivest): C = K2 xor E_K(P xor K1).
+// Ключ 24 байта: K | K1 | K2
+type DESXCipher struct {
+	des    interfaces.BlockCipher
+	pre    []byte
+	post   []byte
+	keySet bool
+}
+
+const DESXKeySize = 24
+
+func NewDESXCipher() interfaces.BlockCipher {
+	return &DESXCipher{des: NewFastDESCipher()}
+}
+
+func (x *DESXCipher) SetKey(key []byte) error {
+	if len(key) != DESXKeySize {
+		return fmt.Errorf("DESX key must be %d bytes, got %d", DESXKeySize, len(key))
+	}
+	if err := x.des.SetKey(key[:8]); err != nil {
+		return err
+	}
+	x.pre = append([]byte(nil), key[8:16]...)
+	x.post = append([]byte(nil), key[16:]...)
+	x.keySet = true
+	return nil
+}
+
+func (x *DESXCipher) EncryptBlock(plaintext []byte) ([]byte, error) {
+	return x.process(plaintext, x.pre, x.post, x.des.EncryptBlock)
+}
+
+func (x *DESXCipher) DecryptBlock(ciphertext []byte) ([]byte, error) {
+	return x.process(ciphertext, x.post, x.pre, x.des.DecryptBlock)
+}
+
+func (x *DESXCipher) process(block, in, out []byte, transform func([]byte) ([]byte, error)) ([]byte, error) {
+	if len(block) != DESBlockSize {
+		return nil, fmt.Errorf("block size must be %d bytes, got %d", DESBlockSize, len(block))
+	}
+	if !x.keySet {
+		return nil, fmt.Errorf("DESX key not set")
+	}
+
+	whitened := make([]byte, DESBlockSize)
+	for i := range whitened {
+		whitened[i] = block[i] ^ in[i]
+	}
+	result, err := transform(whitened)
+	if err != nil {
+		return nil, err
+	}
+	for i := range result {
+		result[i] ^= out[i]
+	}
+	return result, nil
+}
+
+func (x *DESXCipher) BlockSize() int {
+	return DESBlockSize
+}
+
+func (x *DESXCipher) Identify() (interfaces.CipherID, byte) {
+	return interfaces.CipherDESX, 0
+}
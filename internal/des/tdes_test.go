@@ -0,0 +1,196 @@
+package des
+
+import (
+	"bytes"
+	stdcipher "crypto/cipher"
+	"crypto/des"
+	"crypto/rand"
+	"testing"
+
+	"github.com/Qwental/crypota/internal/context"
+	"github.com/Qwental/crypota/internal/interfaces"
+	"github.com/Qwental/crypota/internal/modes"
+	"github.com/Qwental/crypota/internal/padding"
+)
+
+func TestTripleDESAgainstStandardLibrary(t *testing.T) {
+	k1 := []byte{0x01, 0x23, 0x45, 0x67, 0x89, 0xAB, 0xCD, 0xEF}
+	k2 := []byte{0x23, 0x45, 0x67, 0x89, 0xAB, 0xCD, 0xEF, 0x01}
+	k3 := []byte{0x45, 0x67, 0x89, 0xAB, 0xCD, 0xEF, 0x01, 0x23}
+
+	tests := []struct {
+		name   string
+		key    []byte
+		stdKey []byte
+	}{
+		{"independent keys", concat(k1, k2, k3), concat(k1, k2, k3)},
+		{"K1 = K3", concat(k1, k2), concat(k1, k2, k1)},
+		{"K1 = K2 = K3", k1, concat(k1, k1, k1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cipher, err := NewTripleDESCipher(len(tt.key))
+			if err != nil {
+				t.Fatalf("NewTripleDESCipher failed: %v", err)
+			}
+			if err := cipher.SetKey(tt.key); err != nil {
+				t.Fatalf("SetKey failed: %v", err)
+			}
+			stdBlock, _ := des.NewTripleDESCipher(tt.stdKey)
+
+			for i := 0; i < 10; i++ {
+				plaintext := make([]byte, 8)
+				rand.Read(plaintext)
+
+				ciphertext, err := cipher.EncryptBlock(plaintext)
+				if err != nil {
+					t.Fatalf("EncryptBlock failed: %v", err)
+				}
+				expected := make([]byte, 8)
+				stdBlock.Encrypt(expected, plaintext)
+				if !bytes.Equal(ciphertext, expected) {
+					t.Fatalf("Ciphertext %x, want %x", ciphertext, expected)
+				}
+
+				decrypted, err := cipher.DecryptBlock(ciphertext)
+				if err != nil {
+					t.Fatalf("DecryptBlock failed: %v", err)
+				}
+				if !bytes.Equal(decrypted, plaintext) {
+					t.Fatalf("Round-trip failed")
+				}
+			}
+		})
+	}
+
+	// K1 = K2 = K3 вырождается в одинарный DES
+	single := NewDESCipher()
+	single.SetKey(k1)
+	triple, _ := NewTripleDESCipher(8)
+	triple.SetKey(k1)
+	a, _ := single.EncryptBlock(k2)
+	b, _ := triple.EncryptBlock(k2)
+	if !bytes.Equal(a, b) {
+		t.Errorf("3DES with one key must match single DES")
+	}
+
+	if _, err := NewTripleDESCipher(12); err == nil {
+		t.Error("Expected error for 12-byte 3DES key size")
+	}
+	cipher, _ := NewTripleDESCipher(16)
+	if err := cipher.SetKey(make([]byte, 24)); err == nil {
+		t.Error("Expected error for key length not matching key size")
+	}
+}
+
+func TestDESXKnownAnswer(t *testing.T) {
+	key := make([]byte, DESXKeySize)
+	rand.Read(key)
+	plaintext := make([]byte, 8)
+	rand.Read(plaintext)
+
+	cipher := NewDESXCipher()
+	if _, err := cipher.EncryptBlock(plaintext); err == nil {
+		t.Error("Expected error before SetKey")
+	}
+	if err := cipher.SetKey(key); err != nil {
+		t.Fatalf("SetKey failed: %v", err)
+	}
+
+	// C = K2 xor DES_K(P xor K1), считаем через crypto/des
+	stdBlock, _ := des.NewCipher(key[:8])
+	expected := make([]byte, 8)
+	for i := range expected {
+		expected[i] = plaintext[i] ^ key[8+i]
+	}
+	stdBlock.Encrypt(expected, expected)
+	for i := range expected {
+		expected[i] ^= key[16+i]
+	}
+
+	ciphertext, err := cipher.EncryptBlock(plaintext)
+	if err != nil {
+		t.Fatalf("EncryptBlock failed: %v", err)
+	}
+	if !bytes.Equal(ciphertext, expected) {
+		t.Errorf("Ciphertext %x, want %x", ciphertext, expected)
+	}
+	decrypted, err := cipher.DecryptBlock(ciphertext)
+	if err != nil {
+		t.Fatalf("DecryptBlock failed: %v", err)
+	}
+	if !bytes.Equal(decrypted, plaintext) {
+		t.Errorf("Round-trip failed")
+	}
+
+	if err := cipher.SetKey(key[:16]); err == nil {
+		t.Error("Expected error for 16-byte DESX key")
+	}
+}
+
+func TestTripleDESAndDESXWithContext(t *testing.T) {
+	tdes, _ := NewTripleDESCipher(16)
+	ciphers := map[string]struct {
+		cipher interfaces.BlockCipher
+		key    []byte
+	}{
+		"3DES": {tdes, []byte("0123456789abcdef")},
+		"DESX": {NewDESXCipher(), []byte("0123456789abcdefghijklmn")},
+	}
+	// GCM, CCM и OCB определены только для 128-битного блока
+	tested := []modes.CipherMode{
+		modes.ECB, modes.CBC, modes.PCBC, modes.CFB, modes.OFB, modes.CTR, modes.RandomDelta,
+		modes.EAX, modes.CBCCS1, modes.CBCCS2, modes.CBCCS3,
+	}
+	plaintext := []byte("payment terminal message of some length")
+
+	for name, c := range ciphers {
+		for _, mode := range tested {
+			iv := make([]byte, 8)
+			rand.Read(iv)
+			paddingMode := padding.PKCS7
+			if mode >= modes.CBCCS1 && mode <= modes.CBCCS3 {
+				paddingMode = padding.None
+			}
+			ctx, err := context.NewCipherContext(c.cipher, c.key, mode, paddingMode, iv)
+			if err != nil {
+				t.Fatalf("%s mode %d: NewCipherContext failed: %v", name, mode, err)
+			}
+			ciphertext, err := ctx.Encrypt(plaintext)
+			if err != nil {
+				t.Fatalf("%s mode %d: Encrypt failed: %v", name, mode, err)
+			}
+			decrypted, err := ctx.Decrypt(ciphertext)
+			if err != nil {
+				t.Fatalf("%s mode %d: Decrypt failed: %v", name, mode, err)
+			}
+			if !bytes.Equal(decrypted, plaintext) {
+				t.Errorf("%s mode %d: round-trip failed", name, mode)
+			}
+		}
+	}
+
+	// CBC поверх 3DES совпадает с crypto/cipher над crypto/des
+	iv := make([]byte, 8)
+	ctx, _ := context.NewCipherContext(tdes, []byte("0123456789abcdef"), modes.CBC, padding.None, iv)
+	data := plaintext[:32]
+	ciphertext, err := ctx.Encrypt(data)
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+	stdBlock, _ := des.NewTripleDESCipher([]byte("0123456789abcdef01234567"))
+	expected := make([]byte, len(data))
+	stdcipher.NewCBCEncrypter(stdBlock, iv).CryptBlocks(expected, data)
+	if !bytes.Equal(ciphertext, expected) {
+		t.Errorf("3DES-CBC differs from crypto/des")
+	}
+}
+
+func concat(parts ...[]byte) []byte {
+	var out []byte
+	for _, p := range parts {
+		out = append(out, p...)
+	}
+	return out
+}
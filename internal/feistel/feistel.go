@@ -111,3 +111,7 @@ func (fc *FeistelCipher) DecryptBlock(ciphertext []byte) ([]byte, error) {
 	result := append(rightBytes, leftBytes...)
 	return result, nil
 }
+
+func (fc *FeistelCipher) BlockSize() int {
+	return fc.blockSize
+}
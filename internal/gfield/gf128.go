@@ -0,0 +1,98 @@
+package gfield
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// GF128BlockSize размер элемента GF(2^128) в байтах
+const GF128BlockSize = 16
+
+// gcmReduction старшее слово константы R = 11100001 || 0^120 из NIST SP 800-38D
+const gcmReduction = 0xE100000000000000
+
+// MultiplyGF128 умножает два элемента GF(2^128) по модулю x^128 + x^7 + x^2 + x + 1
+// в битовом порядке GCM: нулевой бит элемента - старший бит первого байта
+func MultiplyGF128(x, y []byte) ([]byte, error) {
+	if len(x) != GF128BlockSize || len(y) != GF128BlockSize {
+		return nil, fmt.Errorf("GF(2^128) elements must be %d bytes, got %d and %d", GF128BlockSize, len(x), len(y))
+	}
+
+	xHi, xLo := binary.BigEndian.Uint64(x[:8]), binary.BigEndian.Uint64(x[8:])
+	vHi, vLo := binary.BigEndian.Uint64(y[:8]), binary.BigEndian.Uint64(y[8:])
+	var zHi, zLo uint64
+
+	for i := 0; i < 128; i++ {
+		var bit uint64
+		if i < 64 {
+			bit = (xHi >> (63 - i)) & 1
+		} else {
+			bit = (xLo >> (127 - i)) & 1
+		}
+		if bit == 1 {
+			zHi ^= vHi
+			zLo ^= vLo
+		}
+
+		lsb := vLo & 1
+		vLo = (vLo >> 1) | (vHi << 63)
+		vHi >>= 1
+		if lsb == 1 {
+			vHi ^= gcmReduction
+		}
+	}
+
+	result := make([]byte, GF128BlockSize)
+	binary.BigEndian.PutUint64(result[:8], zHi)
+	binary.BigEndian.PutUint64(result[8:], zLo)
+	return result, nil
+}
+
+// doublingReduction младшие байты неприводимого многочлена для удвоения в GF(2^n)
+// при n = 64, 128, 256 (x^64+x^4+x^3+x+1, x^128+x^7+x^2+x+1, x^256+x^10+x^5+x^2+1)
+var doublingReduction = map[int][]byte{
+	8:  {0x1B},
+	16: {0x87},
+	32: {0x04, 0x25},
+}
+
+// Double умножает блок на x в GF(2^n) в big-endian представлении (dbl из CMAC, OCB, SIV)
+func Double(block []byte) ([]byte, error) {
+	reduction, ok := doublingReduction[len(block)]
+	if !ok {
+		return nil, fmt.Errorf("doubling is not defined for %d-byte blocks", len(block))
+	}
+
+	result := make([]byte, len(block))
+	carry := block[0] >> 7
+	for i := 0; i < len(block)-1; i++ {
+		result[i] = block[i]<<1 | block[i+1]>>7
+	}
+	result[len(block)-1] = block[len(block)-1] << 1
+
+	if carry == 1 {
+		for i, b := range reduction {
+			result[len(result)-len(reduction)+i] ^= b
+		}
+	}
+	return result, nil
+}
+
+// MultiplyByAlpha умножает 128-битный блок на примитивный элемент alpha в GF(2^128)
+// в little-endian представлении IEEE 1619 (XTS): байт 0 - младший
+func MultiplyByAlpha(block []byte) ([]byte, error) {
+	if len(block) != GF128BlockSize {
+		return nil, fmt.Errorf("GF(2^128) element must be %d bytes, got %d", GF128BlockSize, len(block))
+	}
+
+	result := make([]byte, GF128BlockSize)
+	var carry byte
+	for i := 0; i < GF128BlockSize; i++ {
+		result[i] = block[i]<<1 | carry
+		carry = block[i] >> 7
+	}
+	if carry == 1 {
+		result[0] ^= 0x87
+	}
+	return result, nil
+}
@@ -2,6 +2,7 @@ package gfield
 
 import (
 	"bytes"
+	"encoding/hex"
 	"testing"
 )
 
@@ -420,3 +421,113 @@ func polyDivGF256(dividend, divisor []byte, mod byte) ([]byte, []byte) {
 func equalPoly(a, b []byte) bool {
 	return bytes.Equal(trimZeros(a), trimZeros(b))
 }
+
+func TestMultiplyGF128(t *testing.T) {
+	mustHex := func(s string) []byte {
+		b, err := hex.DecodeString(s)
+		if err != nil {
+			t.Fatalf("bad hex %q: %v", s, err)
+		}
+		return b
+	}
+
+	one := make([]byte, GF128BlockSize)
+	one[0] = 0x80
+
+	tests := []struct {
+		name     string
+		x, y     []byte
+		expected []byte
+	}{
+		// тест-кейс 2 из спецификации GCM: X1 = C1 * H
+		{
+			"GCM test case 2",
+			mustHex("0388dace60b6a392f328c2b971b2fe78"),
+			mustHex("66e94bd4ef8a2c3b884cfa59ca342b2e"),
+			mustHex("5e2ec746917062882c85b0685353deb7"),
+		},
+		{
+			"multiply by one",
+			one,
+			mustHex("66e94bd4ef8a2c3b884cfa59ca342b2e"),
+			mustHex("66e94bd4ef8a2c3b884cfa59ca342b2e"),
+		},
+		{
+			"multiply by zero",
+			make([]byte, GF128BlockSize),
+			mustHex("66e94bd4ef8a2c3b884cfa59ca342b2e"),
+			make([]byte, GF128BlockSize),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := MultiplyGF128(tt.x, tt.y)
+			if err != nil {
+				t.Fatalf("MultiplyGF128 failed: %v", err)
+			}
+			if !bytes.Equal(result, tt.expected) {
+				t.Errorf("MultiplyGF128 = %x; want %x", result, tt.expected)
+			}
+		})
+	}
+
+	if _, err := MultiplyGF128(make([]byte, 8), one); err == nil {
+		t.Error("Expected error for short element")
+	}
+}
+
+func TestDouble(t *testing.T) {
+	tests := []struct {
+		name     string
+		block    string
+		expected string
+	}{
+		// подключи CMAC из NIST SP 800-38B для AES-128: L -> K1 -> K2
+		{"AES-128 K1", "7df76b0c1ab899b33e42f047b91b546f", "fbeed618357133667c85e08f7236a8de"},
+		{"AES-128 K2", "fbeed618357133667c85e08f7236a8de", "f7ddac306ae266ccf90bc11ee46d513b"},
+		{"64-bit no carry", "0102030405060708", "020406080a0c0e10"},
+		{"64-bit carry", "8000000000000000", "000000000000001b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			block, _ := hex.DecodeString(tt.block)
+			expected, _ := hex.DecodeString(tt.expected)
+			result, err := Double(block)
+			if err != nil {
+				t.Fatalf("Double failed: %v", err)
+			}
+			if !bytes.Equal(result, expected) {
+				t.Errorf("Double(%s) = %x; want %s", tt.block, result, tt.expected)
+			}
+		})
+	}
+
+	if _, err := Double(make([]byte, 12)); err == nil {
+		t.Error("Expected error for 12-byte block")
+	}
+}
+
+func TestMultiplyByAlpha(t *testing.T) {
+	tests := []struct {
+		name     string
+		block    string
+		expected string
+	}{
+		{"no carry", "01000000000000000000000000000000", "02000000000000000000000000000000"},
+		{"carry between bytes", "80000000000000000000000000000000", "00010000000000000000000000000000"},
+		{"reduction", "00000000000000000000000000000080", "87000000000000000000000000000000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			block, _ := hex.DecodeString(tt.block)
+			expected, _ := hex.DecodeString(tt.expected)
+			result, err := MultiplyByAlpha(block)
+			if err != nil {
+				t.Fatalf("MultiplyByAlpha failed: %v", err)
+			}
+			if !bytes.Equal(result, expected) {
+				t.Errorf("MultiplyByAlpha(%s) = %x; want %s", tt.block, result, tt.expected)
+			}
+		})
+	}
+}
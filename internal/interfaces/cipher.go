@@ -18,3 +18,29 @@ type BlockCipher interface {
 	DecryptBlock(ciphertext []byte) ([]byte, error)	
 	BlockSize() int
 }
+
+// идентификатор алгоритма в заголовке зашифрованного файла
+type CipherID byte
+
+const (
+	CipherUnknown CipherID = iota
+	CipherDES
+	CipherDEAL
+	CipherRijndael
+	CipherTripleDES
+	CipherDESX
+)
+
+// шифр, который может назвать себя в заголовке файла; param - параметр алгоритма
+// (для Rijndael - многочлен поля GF(2^8)), по нему и размерам шифр собирается заново
+type IdentifiedCipher interface {
+	Identify() (id CipherID, param byte)
+}
+
+// шифр, который выгоднее звать сразу на много блоков; режимы ECB и CTR сами переходят
+// на пакетную обработку. Его реализует des.FastDESCipher (bitsliced DES), эталонный
+// des.DESCipher считает по блоку. Длина src кратна BlockSize, dst не короче src
+type BatchCipher interface {
+	EncryptBlocks(dst, src []byte) error
+	DecryptBlocks(dst, src []byte) error
+}
@@ -0,0 +1,166 @@
+package isomac
+
+import (
+	"crypto/subtle"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/Qwental/crypota/internal/des"
+	"github.com/Qwental/crypota/internal/interfaces"
+	"github.com/Qwental/crypota/internal/modes"
+)
+
+// Algorithm - алгоритм MAC из ISO/IEC 9797-1
+type Algorithm int
+
+const (
+	Algorithm1 Algorithm = iota + 1 // CBC-MAC без выходного преобразования
+	Algorithm2                      // CBC-MAC + шифрование результата вторым ключом
+	Algorithm3                      // Retail MAC: CBC-MAC + D(K') и E(K'') над последним блоком
+)
+
+// PaddingMethod - метод набивки из ISO/IEC 9797-1
+type PaddingMethod int
+
+const (
+	Padding1 PaddingMethod = iota + 1 // нули, пустое сообщение - один нулевой блок
+	Padding2                          // 0x80 и нули
+	Padding3                          // блок с длиной в битах в начале и нули в конце
+)
+
+type Config struct {
+	Algorithm Algorithm
+	Padding   PaddingMethod
+	MACLength int // длина MAC в байтах, 0 - весь блок
+}
+
+// ISOMAC считает MAC по ISO/IEC 9797-1 поверх CBC-цепочки из modes
+type ISOMAC struct {
+	config Config
+	chain  interfaces.BlockCipher
+	output []interfaces.BlockCipher
+}
+
+// New принимает шифр цепочки (K) и шифры выходного преобразования:
+// для Algorithm2 - один (K'), для Algorithm3 - один (K', затем снова K) или два (K', K'')
+func New(config Config, chain interfaces.BlockCipher, output ...interfaces.BlockCipher) (*ISOMAC, error) {
+	blockSize := chain.BlockSize()
+	if config.MACLength == 0 {
+		config.MACLength = blockSize
+	}
+	if config.MACLength < 1 || config.MACLength > blockSize {
+		return nil, fmt.Errorf("MAC length must be in [1, %d], got %d", blockSize, config.MACLength)
+	}
+	if config.Padding < Padding1 || config.Padding > Padding3 {
+		return nil, fmt.Errorf("unknown ISO 9797-1 padding method: %d", config.Padding)
+	}
+
+	switch config.Algorithm {
+	case Algorithm1:
+		if len(output) != 0 {
+			return nil, fmt.Errorf("algorithm 1 takes no output transformation keys")
+		}
+	case Algorithm2:
+		if len(output) != 1 {
+			return nil, fmt.Errorf("algorithm 2 requires one output transformation cipher, got %d", len(output))
+		}
+	case Algorithm3:
+		if len(output) == 1 {
+			output = append(output, chain)
+		}
+		if len(output) != 2 {
+			return nil, fmt.Errorf("algorithm 3 requires one or two output transformation ciphers, got %d", len(output))
+		}
+	default:
+		return nil, fmt.Errorf("unknown ISO 9797-1 MAC algorithm: %d", config.Algorithm)
+	}
+
+	for _, c := range output {
+		if c.BlockSize() != blockSize {
+			return nil, fmt.Errorf("output transformation cipher block size %d differs from %d", c.BlockSize(), blockSize)
+		}
+	}
+
+	return &ISOMAC{config: config, chain: chain, output: output}, nil
+}
+
+// NewDES собирает MAC на DES по ключу: 8 байт для Algorithm1, 16 (K || K') для Algorithm2,
+// 16 (K || K') или 24 (K || K' || K'') для Algorithm3 (Retail MAC)
+func NewDES(config Config, key []byte) (*ISOMAC, error) {
+	if len(key)%des.DESBlockSize != 0 || len(key) == 0 {
+		return nil, fmt.Errorf("DES MAC key must be a multiple of 8 bytes, got %d", len(key))
+	}
+
+	ciphers := make([]interfaces.BlockCipher, len(key)/des.DESBlockSize)
+	for i := range ciphers {
+		ciphers[i] = des.NewDESCipher()
+		if err := ciphers[i].SetKey(key[i*des.DESBlockSize : (i+1)*des.DESBlockSize]); err != nil {
+			return nil, fmt.Errorf("failed to set key %d: %w", i+1, err)
+		}
+	}
+	return New(config, ciphers[0], ciphers[1:]...)
+}
+
+// Compute возвращает MAC над data, усеченный до MACLength
+func (m *ISOMAC) Compute(data []byte) ([]byte, error) {
+	blockSize := m.chain.BlockSize()
+	padded := m.pad(data)
+
+	encrypted, err := modes.NewCBCMode(make([]byte, blockSize)).Encrypt(m.chain, padded)
+	if err != nil {
+		return nil, fmt.Errorf("CBC chaining failed: %w", err)
+	}
+	result := encrypted[len(encrypted)-blockSize:]
+
+	switch m.config.Algorithm {
+	case Algorithm2:
+		if result, err = m.output[0].EncryptBlock(result); err != nil {
+			return nil, err
+		}
+	case Algorithm3:
+		if result, err = m.output[0].DecryptBlock(result); err != nil {
+			return nil, err
+		}
+		if result, err = m.output[1].EncryptBlock(result); err != nil {
+			return nil, err
+		}
+	}
+
+	return result[:m.config.MACLength], nil
+}
+
+// Verify сравнивает MAC за постоянное время
+func (m *ISOMAC) Verify(data, mac []byte) (bool, error) {
+	expected, err := m.Compute(data)
+	if err != nil {
+		return false, err
+	}
+	return subtle.ConstantTimeCompare(expected, mac) == 1, nil
+}
+
+func (m *ISOMAC) pad(data []byte) []byte {
+	blockSize := m.chain.BlockSize()
+	var padded []byte
+
+	switch m.config.Padding {
+	case Padding1:
+		padded = append(padded, data...)
+		if len(data) == 0 {
+			padded = make([]byte, blockSize)
+		}
+	case Padding2:
+		padded = append(padded, data...)
+		padded = append(padded, 0x80)
+	case Padding3:
+		lengthBlock := make([]byte, blockSize)
+		bitLength := make([]byte, 8)
+		binary.BigEndian.PutUint64(bitLength, uint64(len(data))*8)
+		copy(lengthBlock[blockSize-8:], bitLength)
+		padded = append(lengthBlock, data...)
+	}
+
+	if rem := len(padded) % blockSize; rem != 0 {
+		padded = append(padded, make([]byte, blockSize-rem)...)
+	}
+	return padded
+}
@@ -0,0 +1,139 @@
+package isomac
+
+import (
+	"bytes"
+	stdcipher "crypto/cipher"
+	stddes "crypto/des"
+	"encoding/hex"
+	"testing"
+)
+
+// referenceMAC считает CBC-MAC и выходное преобразование через crypto/des
+func referenceMAC(t *testing.T, keys [][]byte, padded []byte, algorithm Algorithm) []byte {
+	t.Helper()
+	blocks := make([]stdcipher.Block, len(keys))
+	for i, key := range keys {
+		block, err := stddes.NewCipher(key)
+		if err != nil {
+			t.Fatalf("des.NewCipher failed: %v", err)
+		}
+		blocks[i] = block
+	}
+
+	encrypted := make([]byte, len(padded))
+	stdcipher.NewCBCEncrypter(blocks[0], make([]byte, 8)).CryptBlocks(encrypted, padded)
+	result := encrypted[len(encrypted)-8:]
+
+	switch algorithm {
+	case Algorithm2:
+		blocks[1].Encrypt(result, result)
+	case Algorithm3:
+		last := blocks[0]
+		if len(blocks) == 3 {
+			last = blocks[2]
+		}
+		blocks[1].Decrypt(result, result)
+		last.Encrypt(result, result)
+	}
+	return result
+}
+
+func TestISOMACAgainstStandardLibrary(t *testing.T) {
+	k, _ := hex.DecodeString("0123456789ABCDEF")
+	k2, _ := hex.DecodeString("FEDCBA9876543210")
+	k3, _ := hex.DecodeString("89ABCDEF01234567")
+	data := []byte("Now is the time for it")
+
+	padded := map[PaddingMethod][]byte{
+		Padding1: append([]byte("Now is the time for it"), 0, 0),
+		Padding2: append([]byte("Now is the time for it"), 0x80, 0),
+		Padding3: append(append([]byte{0, 0, 0, 0, 0, 0, 0, 0xB0}, data...), 0, 0),
+	}
+
+	tests := []struct {
+		name      string
+		algorithm Algorithm
+		keys      [][]byte
+	}{
+		{"Algorithm1", Algorithm1, [][]byte{k}},
+		{"Algorithm2", Algorithm2, [][]byte{k, k2}},
+		{"Algorithm3 retail 2-key", Algorithm3, [][]byte{k, k2}},
+		{"Algorithm3 retail 3-key", Algorithm3, [][]byte{k, k2, k3}},
+	}
+
+	for _, tt := range tests {
+		for method := Padding1; method <= Padding3; method++ {
+			key := bytes.Join(tt.keys, nil)
+			mac, err := NewDES(Config{Algorithm: tt.algorithm, Padding: method}, key)
+			if err != nil {
+				t.Fatalf("%s: NewDES failed: %v", tt.name, err)
+			}
+
+			result, err := mac.Compute(data)
+			if err != nil {
+				t.Fatalf("%s: Compute failed: %v", tt.name, err)
+			}
+			expected := referenceMAC(t, tt.keys, padded[method], tt.algorithm)
+			if !bytes.Equal(result, expected) {
+				t.Errorf("%s, padding %d: expected %x, got %x", tt.name, method, expected, result)
+			}
+		}
+	}
+}
+
+func TestISOMACPaddingEdgeCases(t *testing.T) {
+	key, _ := hex.DecodeString("0123456789ABCDEF")
+
+	mac1, _ := NewDES(Config{Algorithm: Algorithm1, Padding: Padding1}, key)
+	mac2, _ := NewDES(Config{Algorithm: Algorithm1, Padding: Padding2}, key)
+
+	empty, err := mac1.Compute(nil)
+	if err != nil {
+		t.Fatalf("Compute failed: %v", err)
+	}
+	zeroBlock, _ := mac1.Compute(make([]byte, 8))
+	if !bytes.Equal(empty, zeroBlock) {
+		t.Errorf("Padding method 1 must turn empty data into one zero block")
+	}
+
+	aligned, _ := mac2.Compute([]byte("12345678"))
+	explicit, _ := mac1.Compute([]byte("12345678\x80\x00\x00\x00\x00\x00\x00\x00"))
+	if !bytes.Equal(aligned, explicit) {
+		t.Errorf("Padding method 2 must add a full block to aligned data")
+	}
+}
+
+func TestISOMACTruncationAndVerify(t *testing.T) {
+	key, _ := hex.DecodeString("0123456789ABCDEFFEDCBA9876543210")
+	data := []byte("PAN=4111111111111111;AMT=000000010000")
+
+	full, err := NewDES(Config{Algorithm: Algorithm3, Padding: Padding2}, key)
+	if err != nil {
+		t.Fatalf("NewDES failed: %v", err)
+	}
+	truncated, err := NewDES(Config{Algorithm: Algorithm3, Padding: Padding2, MACLength: 4}, key)
+	if err != nil {
+		t.Fatalf("NewDES failed: %v", err)
+	}
+
+	fullMAC, _ := full.Compute(data)
+	shortMAC, _ := truncated.Compute(data)
+	if len(shortMAC) != 4 || !bytes.Equal(shortMAC, fullMAC[:4]) {
+		t.Errorf("Truncated MAC %x must be a prefix of %x", shortMAC, fullMAC)
+	}
+
+	if ok, err := truncated.Verify(data, shortMAC); err != nil || !ok {
+		t.Errorf("Verify rejected a valid MAC: %v", err)
+	}
+	data[0] ^= 0x01
+	if ok, _ := truncated.Verify(data, shortMAC); ok {
+		t.Errorf("Verify accepted MAC for modified data")
+	}
+
+	if _, err := NewDES(Config{Algorithm: Algorithm2, Padding: Padding1}, key[:8]); err == nil {
+		t.Error("Expected error for algorithm 2 without second key")
+	}
+	if _, err := NewDES(Config{Algorithm: Algorithm1, Padding: Padding1, MACLength: 9}, key[:8]); err == nil {
+		t.Error("Expected error for MAC longer than block")
+	}
+}
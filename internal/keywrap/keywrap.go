@@ -0,0 +1,179 @@
+package keywrap
+
+import (
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/Qwental/crypota/internal/interfaces"
+)
+
+const (
+	blockSize     = 16
+	semiblockSize = 8
+)
+
+// ErrIntegrityCheckFailed возвращается при развертывании, если проверочное значение не совпало
+var ErrIntegrityCheckFailed = errors.New("key unwrap integrity check failed")
+
+// defaultIV - начальное значение A6A6A6A6A6A6A6A6 из RFC 3394
+var defaultIV = []byte{0xA6, 0xA6, 0xA6, 0xA6, 0xA6, 0xA6, 0xA6, 0xA6}
+
+// alternativeIVPrefix - старшая половина AIV из RFC 5649, младшая - длина ключа
+var alternativeIVPrefix = []byte{0xA6, 0x59, 0x59, 0xA6}
+
+func checkCipher(kek interfaces.BlockCipher) error {
+	if kek.BlockSize() != blockSize {
+		return fmt.Errorf("key wrap requires %d-byte block cipher, got %d", blockSize, kek.BlockSize())
+	}
+	return nil
+}
+
+// Wrap оборачивает ключ по RFC 3394, длина ключа - кратная 8 и не меньше 16 байт
+func Wrap(kek interfaces.BlockCipher, key []byte) ([]byte, error) {
+	if err := checkCipher(kek); err != nil {
+		return nil, err
+	}
+	if len(key) < 2*semiblockSize || len(key)%semiblockSize != 0 {
+		return nil, fmt.Errorf("key to wrap must be a multiple of %d bytes and at least %d bytes, got %d", semiblockSize, 2*semiblockSize, len(key))
+	}
+	return wrap(kek, defaultIV, key)
+}
+
+// Unwrap разворачивает ключ по RFC 3394 и проверяет значение целостности
+func Unwrap(kek interfaces.BlockCipher, wrapped []byte) ([]byte, error) {
+	if err := checkCipher(kek); err != nil {
+		return nil, err
+	}
+	if len(wrapped) < 3*semiblockSize || len(wrapped)%semiblockSize != 0 {
+		return nil, fmt.Errorf("wrapped key must be a multiple of %d bytes and at least %d bytes, got %d", semiblockSize, 3*semiblockSize, len(wrapped))
+	}
+	a, key, err := unwrap(kek, wrapped)
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare(a, defaultIV) != 1 {
+		return nil, ErrIntegrityCheckFailed
+	}
+	return key, nil
+}
+
+// WrapWithPadding оборачивает ключ произвольной ненулевой длины по RFC 5649
+func WrapWithPadding(kek interfaces.BlockCipher, key []byte) ([]byte, error) {
+	if err := checkCipher(kek); err != nil {
+		return nil, err
+	}
+	if len(key) == 0 || uint64(len(key)) > 0xFFFFFFFF {
+		return nil, fmt.Errorf("key to wrap must be 1..2^32-1 bytes, got %d", len(key))
+	}
+
+	aiv := make([]byte, semiblockSize)
+	copy(aiv, alternativeIVPrefix)
+	binary.BigEndian.PutUint32(aiv[4:], uint32(len(key)))
+
+	padded := make([]byte, (len(key)+semiblockSize-1)/semiblockSize*semiblockSize)
+	copy(padded, key)
+
+	// единственный полублок шифруется одним блоком без раундов обертки
+	if len(padded) == semiblockSize {
+		return kek.EncryptBlock(append(aiv, padded...))
+	}
+	return wrap(kek, aiv, padded)
+}
+
+// UnwrapWithPadding разворачивает ключ по RFC 5649, проверяя AIV, длину и нулевую набивку
+func UnwrapWithPadding(kek interfaces.BlockCipher, wrapped []byte) ([]byte, error) {
+	if err := checkCipher(kek); err != nil {
+		return nil, err
+	}
+	if len(wrapped) < 2*semiblockSize || len(wrapped)%semiblockSize != 0 {
+		return nil, fmt.Errorf("wrapped key must be a multiple of %d bytes and at least %d bytes, got %d", semiblockSize, 2*semiblockSize, len(wrapped))
+	}
+
+	var a, padded []byte
+	if len(wrapped) == 2*semiblockSize {
+		block, err := kek.DecryptBlock(wrapped)
+		if err != nil {
+			return nil, err
+		}
+		a, padded = block[:semiblockSize], block[semiblockSize:]
+	} else {
+		var err error
+		if a, padded, err = unwrap(kek, wrapped); err != nil {
+			return nil, err
+		}
+	}
+
+	if subtle.ConstantTimeCompare(a[:4], alternativeIVPrefix) != 1 {
+		return nil, ErrIntegrityCheckFailed
+	}
+	keyLen := int(binary.BigEndian.Uint32(a[4:]))
+	if keyLen <= len(padded)-semiblockSize || keyLen > len(padded) {
+		return nil, ErrIntegrityCheckFailed
+	}
+	for _, b := range padded[keyLen:] {
+		if b != 0 {
+			return nil, ErrIntegrityCheckFailed
+		}
+	}
+	return padded[:keyLen], nil
+}
+
+// wrap - процедура W из RFC 3394 (индексная форма) с начальным значением iv
+func wrap(kek interfaces.BlockCipher, iv, key []byte) ([]byte, error) {
+	n := len(key) / semiblockSize
+	a := make([]byte, semiblockSize)
+	copy(a, iv)
+	r := make([]byte, len(key))
+	copy(r, key)
+
+	block := make([]byte, blockSize)
+	for j := 0; j < 6; j++ {
+		for i := 0; i < n; i++ {
+			copy(block, a)
+			copy(block[semiblockSize:], r[i*semiblockSize:(i+1)*semiblockSize])
+			b, err := kek.EncryptBlock(block)
+			if err != nil {
+				return nil, fmt.Errorf("key wrap encryption failed: %w", err)
+			}
+			copy(a, b[:semiblockSize])
+			xorCounter(a, uint64(n*j+i+1))
+			copy(r[i*semiblockSize:], b[semiblockSize:])
+		}
+	}
+	return append(a, r...), nil
+}
+
+// unwrap - обратная процедура W^-1, возвращает восстановленное A и ключ без проверки
+func unwrap(kek interfaces.BlockCipher, wrapped []byte) ([]byte, []byte, error) {
+	n := len(wrapped)/semiblockSize - 1
+	a := make([]byte, semiblockSize)
+	copy(a, wrapped[:semiblockSize])
+	r := make([]byte, len(wrapped)-semiblockSize)
+	copy(r, wrapped[semiblockSize:])
+
+	block := make([]byte, blockSize)
+	for j := 5; j >= 0; j-- {
+		for i := n - 1; i >= 0; i-- {
+			xorCounter(a, uint64(n*j+i+1))
+			copy(block, a)
+			copy(block[semiblockSize:], r[i*semiblockSize:(i+1)*semiblockSize])
+			b, err := kek.DecryptBlock(block)
+			if err != nil {
+				return nil, nil, fmt.Errorf("key unwrap decryption failed: %w", err)
+			}
+			copy(a, b[:semiblockSize])
+			copy(r[i*semiblockSize:], b[semiblockSize:])
+		}
+	}
+	return a, r, nil
+}
+
+func xorCounter(a []byte, t uint64) {
+	var counter [semiblockSize]byte
+	binary.BigEndian.PutUint64(counter[:], t)
+	for i := range counter {
+		a[i] ^= counter[i]
+	}
+}
@@ -0,0 +1,161 @@
+package keywrap
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/Qwental/crypota/internal/deal"
+	"github.com/Qwental/crypota/internal/rijndael"
+	"github.com/Qwental/crypota/internal/stdblock"
+)
+
+// newAESKEK - AES из стандартной библиотеки для проверки по векторам RFC
+func newAESKEK(t *testing.T, key string) *stdblock.BlockCipher {
+	t.Helper()
+	decoded, _ := hex.DecodeString(key)
+	kek, err := stdblock.NewBlockCipherFunc(aes.NewCipher, decoded)
+	if err != nil {
+		t.Fatalf("aes.NewCipher failed: %v", err)
+	}
+	return kek
+}
+
+func TestWrapRFC3394(t *testing.T) {
+	tests := []struct {
+		name, kek, key, wrapped string
+	}{
+		{"4.1 128-bit KEK, 128-bit key", "000102030405060708090A0B0C0D0E0F", "00112233445566778899AABBCCDDEEFF", "1FA68B0A8112B447AEF34BD8FB5A7B829D3E862371D2CFE5"},
+		{"4.2 192-bit KEK, 128-bit key", "000102030405060708090A0B0C0D0E0F1011121314151617", "00112233445566778899AABBCCDDEEFF", "96778B25AE6CA435F92B5B97C050AED2468AB8A17AD84E5D"},
+		{"4.6 256-bit KEK, 256-bit key", "000102030405060708090A0B0C0D0E0F101112131415161718191A1B1C1D1E1F", "00112233445566778899AABBCCDDEEFF000102030405060708090A0B0C0D0E0F", "28C9F404C4B810F4CBCCB35CFB87F8263F5786E2D80ED326CBC7F0E71A99F43BFB988B9B7A02DD21"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kek := newAESKEK(t, tt.kek)
+			key, _ := hex.DecodeString(tt.key)
+			expected, _ := hex.DecodeString(tt.wrapped)
+
+			wrapped, err := Wrap(kek, key)
+			if err != nil {
+				t.Fatalf("Wrap failed: %v", err)
+			}
+			if !bytes.Equal(wrapped, expected) {
+				t.Fatalf("Mismatch\nExpected: %x\nGot:      %x", expected, wrapped)
+			}
+
+			unwrapped, err := Unwrap(kek, wrapped)
+			if err != nil {
+				t.Fatalf("Unwrap failed: %v", err)
+			}
+			if !bytes.Equal(unwrapped, key) {
+				t.Errorf("Unwrapped key mismatch")
+			}
+		})
+	}
+}
+
+func TestWrapWithPaddingRFC5649(t *testing.T) {
+	kek := newAESKEK(t, "5840df6e29b02af1ab493b705bf16ea1ae8338f4dcc176a8")
+	tests := []struct {
+		name, key, wrapped string
+	}{
+		{"20-byte key", "c37b7e6492584340bed12207808941155068f738", "138bdeaa9b8fa7fc61f97742e72248ee5ae6ae5360d1ae6a5f54f373fa543b6a"},
+		{"7-byte key", "466f7250617369", "afbeb0f07dfbf5419200f2ccb50bb24f"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, _ := hex.DecodeString(tt.key)
+			expected, _ := hex.DecodeString(tt.wrapped)
+
+			wrapped, err := WrapWithPadding(kek, key)
+			if err != nil {
+				t.Fatalf("WrapWithPadding failed: %v", err)
+			}
+			if !bytes.Equal(wrapped, expected) {
+				t.Fatalf("Mismatch\nExpected: %x\nGot:      %x", expected, wrapped)
+			}
+
+			unwrapped, err := UnwrapWithPadding(kek, wrapped)
+			if err != nil {
+				t.Fatalf("UnwrapWithPadding failed: %v", err)
+			}
+			if !bytes.Equal(unwrapped, key) {
+				t.Errorf("Unwrapped key mismatch")
+			}
+		})
+	}
+}
+
+func TestUnwrapDetectsTampering(t *testing.T) {
+	kek, err := rijndael.NewRijndaelCipher(16, 32, 0x1B)
+	if err != nil {
+		t.Fatalf("NewRijndaelCipher failed: %v", err)
+	}
+	kekKey := make([]byte, 32)
+	rand.Read(kekKey)
+	if err := kek.SetKey(kekKey); err != nil {
+		t.Fatalf("SetKey failed: %v", err)
+	}
+
+	dealKey := make([]byte, 24)
+	rand.Read(dealKey)
+	wrapped, err := Wrap(kek, dealKey)
+	if err != nil {
+		t.Fatalf("Wrap failed: %v", err)
+	}
+	wrapped[5] ^= 0x01
+	if _, err := Unwrap(kek, wrapped); !errors.Is(err, ErrIntegrityCheckFailed) {
+		t.Errorf("Expected ErrIntegrityCheckFailed, got %v", err)
+	}
+
+	desKey := []byte{0x13, 0x34, 0x57, 0x79, 0x9B, 0xBC, 0xDF, 0xF1}
+	wrappedDES, err := WrapWithPadding(kek, desKey)
+	if err != nil {
+		t.Fatalf("WrapWithPadding failed: %v", err)
+	}
+	if len(wrappedDES) != 16 {
+		t.Errorf("Single-semiblock key must wrap into one block, got %d bytes", len(wrappedDES))
+	}
+	if _, err := Unwrap(kek, append(wrappedDES, make([]byte, 8)...)); !errors.Is(err, ErrIntegrityCheckFailed) {
+		t.Errorf("Expected ErrIntegrityCheckFailed for padded wrap unwrapped as plain, got %v", err)
+	}
+	wrappedDES[15] ^= 0x80
+	if _, err := UnwrapWithPadding(kek, wrappedDES); !errors.Is(err, ErrIntegrityCheckFailed) {
+		t.Errorf("Expected ErrIntegrityCheckFailed, got %v", err)
+	}
+}
+
+func TestWrapWithDEALKEK(t *testing.T) {
+	kek, err := deal.NewDEALCipher(16)
+	if err != nil {
+		t.Fatalf("NewDEALCipher failed: %v", err)
+	}
+	if err := kek.SetKey([]byte("0123456789abcdef")); err != nil {
+		t.Fatalf("SetKey failed: %v", err)
+	}
+
+	for _, size := range []int{1, 8, 13, 16, 24, 32} {
+		key := make([]byte, size)
+		rand.Read(key)
+		wrapped, err := WrapWithPadding(kek, key)
+		if err != nil {
+			t.Fatalf("WrapWithPadding failed: %v", err)
+		}
+		unwrapped, err := UnwrapWithPadding(kek, wrapped)
+		if err != nil {
+			t.Fatalf("UnwrapWithPadding failed: %v", err)
+		}
+		if !bytes.Equal(unwrapped, key) {
+			t.Errorf("Round-trip failed for %d-byte key", size)
+		}
+	}
+
+	if _, err := Wrap(kek, make([]byte, 12)); err == nil {
+		t.Error("Expected error for key that is not a multiple of 8 bytes")
+	}
+}
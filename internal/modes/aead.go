@@ -0,0 +1,21 @@
+package modes
+
+import "github.com/Qwental/crypota/internal/interfaces"
+
+// AEADMode общий интерфейс режимов с аутентификацией (GCM, CCM, EAX, OCB).
+// Seal возвращает шифртекст с тегом в конце, Open проверяет тег и не отдает
+// открытый текст, если проверка не прошла (ErrAuthenticationFailed)
+type AEADMode interface {
+	Mode
+	Seal(cipher interfaces.BlockCipher, nonce, plaintext, additionalData []byte) ([]byte, error)
+	Open(cipher interfaces.BlockCipher, nonce, ciphertext, additionalData []byte) ([]byte, error)
+	Overhead() int
+}
+
+func (m *GCMMode) Overhead() int {
+	return GCMTagSize
+}
+
+func (m *CCMMode) Overhead() int {
+	return m.tagSize
+}
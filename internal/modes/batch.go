@@ -0,0 +1,45 @@
+package modes
+
+import (
+	"fmt"
+
+	"github.com/Qwental/crypota/internal/interfaces"
+)
+
+// batchBlocks - сколько блоков режим собирает в один вызов BatchCipher, когда ему
+// нужен промежуточный буфер (счетчики CTR); равно числу полос bitsliced DES
+const batchBlocks = 64
+
+// cryptBlocks обрабатывает целые блоки src в dst: шифр с interfaces.BatchCipher получает
+// их одним вызовом, остальные - по блоку. first - номер первого блока для сообщений об ошибках
+func cryptBlocks(cipher interfaces.BlockCipher, dst, src []byte, first int, decrypt bool) error {
+	action := "encryption"
+	if decrypt {
+		action = "decryption"
+	}
+
+	if batch, ok := cipher.(interfaces.BatchCipher); ok {
+		process := batch.EncryptBlocks
+		if decrypt {
+			process = batch.DecryptBlocks
+		}
+		if err := process(dst, src); err != nil {
+			return fmt.Errorf("blocks %d-%d %s failed: %w", first, first+len(src)/cipher.BlockSize()-1, action, err)
+		}
+		return nil
+	}
+
+	process := cipher.EncryptBlock
+	if decrypt {
+		process = cipher.DecryptBlock
+	}
+	blockSize := cipher.BlockSize()
+	for offset := 0; offset < len(src); offset += blockSize {
+		block, err := process(src[offset : offset+blockSize])
+		if err != nil {
+			return fmt.Errorf("block %d %s failed: %w", first+offset/blockSize, action, err)
+		}
+		copy(dst[offset:offset+blockSize], block)
+	}
+	return nil
+}
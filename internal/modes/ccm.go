@@ -0,0 +1,191 @@
+package modes
+
+import (
+	"crypto/subtle"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/Qwental/crypota/internal/interfaces"
+)
+
+const (
+	CCMBlockSize  = 16
+	CCMMinNonce   = 7
+	CCMMaxNonce   = 13
+	CCMMinTagSize = 4
+	CCMMaxTagSize = 16
+	CCMDefaultTag = 16
+)
+
+// TagSize длина тега аутентификации в байтах, передается в params контекста
+type TagSize int
+
+// CCMMode Counter with CBC-MAC (NIST SP 800-38C): тег считается CBC-MAC над
+// отформатированными B0, ассоциированными данными и открытым текстом, а данные
+// шифруются в CTR со счетчиком, начинающимся с A1
+type CCMMode struct {
+	nonce          []byte
+	additionalData []byte
+	tagSize        int
+}
+
+// NewCCMMode проверяет длину тега и nonce; nil nonce допустим, если nonce передается в Seal/Open
+func NewCCMMode(nonce []byte, tagSize int, additionalData []byte) (*CCMMode, error) {
+	if tagSize < CCMMinTagSize || tagSize > CCMMaxTagSize || tagSize%2 != 0 {
+		return nil, fmt.Errorf("CCM tag size must be even and in [%d, %d], got %d", CCMMinTagSize, CCMMaxTagSize, tagSize)
+	}
+	if nonce != nil && (len(nonce) < CCMMinNonce || len(nonce) > CCMMaxNonce) {
+		return nil, fmt.Errorf("CCM nonce must be %d..%d bytes, got %d", CCMMinNonce, CCMMaxNonce, len(nonce))
+	}
+	return &CCMMode{nonce: nonce, additionalData: additionalData, tagSize: tagSize}, nil
+}
+
+func (m *CCMMode) Encrypt(cipher interfaces.BlockCipher, plaintext []byte) ([]byte, error) {
+	return m.Seal(cipher, m.nonce, plaintext, m.additionalData)
+}
+
+func (m *CCMMode) Decrypt(cipher interfaces.BlockCipher, ciphertext []byte) ([]byte, error) {
+	return m.Open(cipher, m.nonce, ciphertext, m.additionalData)
+}
+
+// Seal шифрует plaintext и возвращает шифртекст с дописанным в конец тегом
+func (m *CCMMode) Seal(cipher interfaces.BlockCipher, nonce, plaintext, additionalData []byte) ([]byte, error) {
+	if err := m.check(cipher, nonce, len(plaintext)); err != nil {
+		return nil, err
+	}
+
+	tag, err := m.mac(cipher, nonce, plaintext, additionalData)
+	if err != nil {
+		return nil, err
+	}
+
+	// шифруем тег и данные одним проходом CTR начиная с A0
+	ciphertext, err := NewCTRMode(ccmCounter(nonce, 0)).Encrypt(cipher, append(tag, plaintext...))
+	if err != nil {
+		return nil, err
+	}
+
+	output := make([]byte, 0, len(plaintext)+m.tagSize)
+	output = append(output, ciphertext[CCMBlockSize:]...)
+	output = append(output, ciphertext[:m.tagSize]...)
+	return output, nil
+}
+
+// Open расшифровывает данные и отдает их только если тег сошелся
+func (m *CCMMode) Open(cipher interfaces.BlockCipher, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(ciphertext) < m.tagSize {
+		return nil, fmt.Errorf("ciphertext too short for CCM tag")
+	}
+	payloadLen := len(ciphertext) - m.tagSize
+	if err := m.check(cipher, nonce, payloadLen); err != nil {
+		return nil, err
+	}
+
+	encryptedTag := make([]byte, CCMBlockSize)
+	copy(encryptedTag, ciphertext[payloadLen:])
+
+	decrypted, err := NewCTRMode(ccmCounter(nonce, 0)).Decrypt(cipher, append(encryptedTag, ciphertext[:payloadLen]...))
+	if err != nil {
+		return nil, err
+	}
+	receivedTag := decrypted[:m.tagSize]
+	plaintext := decrypted[CCMBlockSize:]
+
+	expectedTag, err := m.mac(cipher, nonce, plaintext, additionalData)
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare(expectedTag[:m.tagSize], receivedTag) != 1 {
+		for i := range plaintext {
+			plaintext[i] = 0
+		}
+		return nil, ErrAuthenticationFailed
+	}
+	return plaintext, nil
+}
+
+func (m *CCMMode) check(cipher interfaces.BlockCipher, nonce []byte, payloadLen int) error {
+	if cipher.BlockSize() != CCMBlockSize {
+		return fmt.Errorf("CCM requires %d-byte block cipher, got %d", CCMBlockSize, cipher.BlockSize())
+	}
+	if len(nonce) < CCMMinNonce || len(nonce) > CCMMaxNonce {
+		return fmt.Errorf("CCM nonce must be %d..%d bytes, got %d", CCMMinNonce, CCMMaxNonce, len(nonce))
+	}
+	q := 15 - len(nonce)
+	if q < 8 && uint64(payloadLen) >= uint64(1)<<(8*q) {
+		return fmt.Errorf("CCM payload of %d bytes does not fit into %d-byte length field", payloadLen, q)
+	}
+	return nil
+}
+
+// mac считает CBC-MAC над B0 || закодированные ассоциированные данные || открытый текст;
+// результат - полный блок, обрезать до длины тега будет вызывающий
+func (m *CCMMode) mac(cipher interfaces.BlockCipher, nonce, plaintext, additionalData []byte) ([]byte, error) {
+	q := 15 - len(nonce)
+
+	b0 := make([]byte, CCMBlockSize)
+	if len(additionalData) > 0 {
+		b0[0] |= 0x40
+	}
+	b0[0] |= byte((m.tagSize-2)/2) << 3
+	b0[0] |= byte(q - 1)
+	copy(b0[1:], nonce)
+	length := make([]byte, 8)
+	binary.BigEndian.PutUint64(length, uint64(len(plaintext)))
+	copy(b0[CCMBlockSize-q:], length[8-q:])
+
+	formatted := b0
+	if len(additionalData) > 0 {
+		formatted = append(formatted, encodeCCMAdditionalDataLength(len(additionalData))...)
+		formatted = append(formatted, additionalData...)
+		formatted = zeroPadToBlock(formatted, CCMBlockSize)
+	}
+	formatted = append(formatted, plaintext...)
+	formatted = zeroPadToBlock(formatted, CCMBlockSize)
+
+	return cbcMAC(cipher, formatted)
+}
+
+func encodeCCMAdditionalDataLength(n int) []byte {
+	switch {
+	case n < 0xFF00:
+		return []byte{byte(n >> 8), byte(n)}
+	case uint64(n) <= 0xFFFFFFFF:
+		encoded := []byte{0xFF, 0xFE, 0, 0, 0, 0}
+		binary.BigEndian.PutUint32(encoded[2:], uint32(n))
+		return encoded
+	default:
+		encoded := []byte{0xFF, 0xFF, 0, 0, 0, 0, 0, 0, 0, 0}
+		binary.BigEndian.PutUint64(encoded[2:], uint64(n))
+		return encoded
+	}
+}
+
+// ccmCounter формирует блок счетчика Ai = flags || nonce || [i]q
+func ccmCounter(nonce []byte, i uint64) []byte {
+	q := 15 - len(nonce)
+	counter := make([]byte, CCMBlockSize)
+	counter[0] = byte(q - 1)
+	copy(counter[1:], nonce)
+	index := make([]byte, 8)
+	binary.BigEndian.PutUint64(index, i)
+	copy(counter[CCMBlockSize-q:], index[8-q:])
+	return counter
+}
+
+// cbcMAC - последний блок CBC-шифрования с нулевым IV
+func cbcMAC(cipher interfaces.BlockCipher, data []byte) ([]byte, error) {
+	blockSize := cipher.BlockSize()
+	encrypted, err := NewCBCMode(make([]byte, blockSize)).Encrypt(cipher, data)
+	if err != nil {
+		return nil, fmt.Errorf("CBC-MAC failed: %w", err)
+	}
+	return encrypted[len(encrypted)-blockSize:], nil
+}
+
+func zeroPadToBlock(data []byte, blockSize int) []byte {
+	if rem := len(data) % blockSize; rem != 0 {
+		data = append(data, make([]byte, blockSize-rem)...)
+	}
+	return data
+}
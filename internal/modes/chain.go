@@ -0,0 +1,79 @@
+package modes
+
+// ChainableMode режим, который можно продолжить на следующем фрагменте данных.
+// Next вызывается после обработки фрагмента из целого числа блоков и возвращает
+// новый режим, состояние которого продолжает цепочку; исходный режим не меняется
+type ChainableMode interface {
+	Mode
+	Next(plaintext, ciphertext []byte) Mode
+}
+
+// AlignedMode режим, которому для Next нужны фрагменты длиной, кратной Alignment() байт
+// (например, CFB-s с сегментом, не делящим байт)
+type AlignedMode interface {
+	Alignment() int
+}
+
+func lastBlock(data []byte, blockSize int) []byte {
+	block := make([]byte, blockSize)
+	copy(block, data[len(data)-blockSize:])
+	return block
+}
+
+func xorLastBlocks(a, b []byte, blockSize int) []byte {
+	block := lastBlock(a, blockSize)
+	tail := b[len(b)-blockSize:]
+	for i := range block {
+		block[i] ^= tail[i]
+	}
+	return block
+}
+
+func (m *ECBMode) Next(plaintext, ciphertext []byte) Mode {
+	return m
+}
+
+func (m *CBCMode) Next(plaintext, ciphertext []byte) Mode {
+	if len(ciphertext) == 0 {
+		return m
+	}
+	return NewCBCMode(lastBlock(ciphertext, len(m.iv)))
+}
+
+func (m *PCBCMode) Next(plaintext, ciphertext []byte) Mode {
+	if len(ciphertext) == 0 {
+		return m
+	}
+	return NewPCBCMode(xorLastBlocks(plaintext, ciphertext, len(m.iv)))
+}
+
+func (m *CFBMode) Next(plaintext, ciphertext []byte) Mode {
+	if len(ciphertext) == 0 {
+		return m
+	}
+	return NewCFBSegmentMode(lastBlock(ciphertext, len(m.iv)), m.segmentBits)
+}
+
+// для OFB новая обратная связь - последний блок гаммы, то есть P xor C (и для OFB-s тоже)
+func (m *OFBMode) Next(plaintext, ciphertext []byte) Mode {
+	if len(ciphertext) == 0 {
+		return m
+	}
+	return NewOFBSegmentMode(xorLastBlocks(plaintext, ciphertext, len(m.iv)), m.segmentBits)
+}
+
+func (m *CTRMode) Next(plaintext, ciphertext []byte) Mode {
+	counter := make([]byte, len(m.iv))
+	copy(counter, m.iv)
+	incrementCounterBy(counter, len(ciphertext)/len(m.iv))
+	return NewCTRMode(counter)
+}
+
+func (m *RandomDeltaMode) Next(plaintext, ciphertext []byte) Mode {
+	current := make([]byte, len(m.iv))
+	copy(current, m.iv)
+	for i := 0; i < len(ciphertext)/len(m.iv); i++ {
+		addDelta(current, m.delta)
+	}
+	return &RandomDeltaMode{iv: current, delta: m.delta}
+}
@@ -0,0 +1,139 @@
+package modes
+
+import (
+	"fmt"
+
+	"github.com/Qwental/crypota/internal/interfaces"
+)
+
+// StealingVariant - вариант кражи шифртекста из дополнения к NIST SP 800-38A
+type StealingVariant int
+
+const (
+	CS1 StealingVariant = iota + 1 // неполный предпоследний блок остается на месте
+	CS2                            // два последних блока меняются местами, только если последний неполный
+	CS3                            // два последних блока меняются местами всегда (как в Kerberos)
+)
+
+// CBCCSMode CBC с кражей шифртекста: длина шифртекста равна длине открытого текста
+// для любого сообщения не короче одного блока, набивка не нужна
+type CBCCSMode struct {
+	iv      []byte
+	variant StealingVariant
+}
+
+func NewCBCCSMode(iv []byte, variant StealingVariant) *CBCCSMode {
+	return &CBCCSMode{iv: iv, variant: variant}
+}
+
+func (m *CBCCSMode) check(blockSize, length int) error {
+	if m.variant < CS1 || m.variant > CS3 {
+		return fmt.Errorf("unknown ciphertext stealing variant: %d", m.variant)
+	}
+	if len(m.iv) != blockSize {
+		return fmt.Errorf("IV length must equal block size")
+	}
+	if length < blockSize {
+		return fmt.Errorf("ciphertext stealing requires at least one full block, got %d bytes", length)
+	}
+	return nil
+}
+
+func (m *CBCCSMode) Encrypt(cipher interfaces.BlockCipher, plaintext []byte) ([]byte, error) {
+	blockSize := cipher.BlockSize()
+	if err := m.check(blockSize, len(plaintext)); err != nil {
+		return nil, err
+	}
+
+	tail := len(plaintext) % blockSize
+	padded := zeroPadToBlock(append([]byte(nil), plaintext...), blockSize)
+	encrypted, err := NewCBCMode(m.iv).Encrypt(cipher, padded)
+	if err != nil {
+		return nil, err
+	}
+	if len(encrypted) == blockSize {
+		return encrypted, nil
+	}
+
+	// раскладка CS1: C1 .. C(n-2) || MSB_d(C(n-1)) || Cn
+	d := blockSize
+	if tail != 0 {
+		d = tail
+	}
+	lastStart := len(encrypted) - blockSize
+	prevStart := lastStart - blockSize
+	output := make([]byte, 0, len(plaintext))
+	output = append(output, encrypted[:prevStart]...)
+
+	if m.swaps(tail) {
+		output = append(output, encrypted[lastStart:]...)
+		output = append(output, encrypted[prevStart:prevStart+d]...)
+	} else {
+		output = append(output, encrypted[prevStart:prevStart+d]...)
+		output = append(output, encrypted[lastStart:]...)
+	}
+	return output, nil
+}
+
+func (m *CBCCSMode) Decrypt(cipher interfaces.BlockCipher, ciphertext []byte) ([]byte, error) {
+	blockSize := cipher.BlockSize()
+	if err := m.check(blockSize, len(ciphertext)); err != nil {
+		return nil, err
+	}
+	if len(ciphertext) == blockSize {
+		return NewCBCMode(m.iv).Decrypt(cipher, ciphertext)
+	}
+
+	tail := len(ciphertext) % blockSize
+	d := blockSize
+	if tail != 0 {
+		d = tail
+	}
+	n := (len(ciphertext) + blockSize - 1) / blockSize
+	prevStart := (n - 2) * blockSize
+
+	// приводим к раскладке CS1
+	var partial, last []byte
+	if m.swaps(tail) {
+		last = ciphertext[prevStart : prevStart+blockSize]
+		partial = ciphertext[prevStart+blockSize:]
+	} else {
+		partial = ciphertext[prevStart : prevStart+d]
+		last = ciphertext[prevStart+d:]
+	}
+
+	// D(Cn) = Pn xor C(n-1), а хвост Pn нулевой, значит хвост D(Cn) - украденная часть C(n-1)
+	z, err := cipher.DecryptBlock(last)
+	if err != nil {
+		return nil, err
+	}
+	prev := make([]byte, blockSize)
+	copy(prev, partial)
+	copy(prev[d:], z[d:])
+
+	full := make([]byte, 0, n*blockSize)
+	full = append(full, ciphertext[:prevStart]...)
+	full = append(full, prev...)
+	head, err := NewCBCMode(m.iv).Decrypt(cipher, full)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext := make([]byte, len(ciphertext))
+	copy(plaintext, head)
+	for i := 0; i < d; i++ {
+		plaintext[prevStart+blockSize+i] = z[i] ^ prev[i]
+	}
+	return plaintext, nil
+}
+
+func (m *CBCCSMode) swaps(tail int) bool {
+	switch m.variant {
+	case CS2:
+		return tail != 0
+	case CS3:
+		return true
+	default:
+		return false
+	}
+}
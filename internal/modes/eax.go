@@ -0,0 +1,112 @@
+package modes
+
+import (
+	"crypto/subtle"
+	"fmt"
+
+	"github.com/Qwental/crypota/internal/cmac"
+	"github.com/Qwental/crypota/internal/interfaces"
+)
+
+// EAXMode EAX (Bellare, Rogaway, Wagner): CTR с начальным счетчиком OMAC(0 || nonce)
+// и тег OMAC(0 || nonce) xor OMAC(1 || header) xor OMAC(2 || ciphertext).
+// Работает с любым шифром, для размера блока которого определено удвоение (8, 16, 32 байта)
+type EAXMode struct {
+	nonce          []byte
+	additionalData []byte
+	tagSize        int
+}
+
+func NewEAXMode(nonce []byte, tagSize int, additionalData []byte) (*EAXMode, error) {
+	if tagSize <= 0 {
+		return nil, fmt.Errorf("EAX tag size must be positive, got %d", tagSize)
+	}
+	return &EAXMode{nonce: nonce, additionalData: additionalData, tagSize: tagSize}, nil
+}
+
+func (m *EAXMode) Encrypt(cipher interfaces.BlockCipher, plaintext []byte) ([]byte, error) {
+	return m.Seal(cipher, m.nonce, plaintext, m.additionalData)
+}
+
+func (m *EAXMode) Decrypt(cipher interfaces.BlockCipher, ciphertext []byte) ([]byte, error) {
+	return m.Open(cipher, m.nonce, ciphertext, m.additionalData)
+}
+
+func (m *EAXMode) Overhead() int {
+	return m.tagSize
+}
+
+func (m *EAXMode) Seal(cipher interfaces.BlockCipher, nonce, plaintext, additionalData []byte) ([]byte, error) {
+	if err := m.check(cipher); err != nil {
+		return nil, err
+	}
+
+	nonceMAC, err := omacTweaked(cipher, 0, nonce)
+	if err != nil {
+		return nil, err
+	}
+	ciphertext, err := NewCTRMode(nonceMAC).Encrypt(cipher, plaintext)
+	if err != nil {
+		return nil, err
+	}
+	tag, err := m.tag(cipher, nonceMAC, ciphertext, additionalData)
+	if err != nil {
+		return nil, err
+	}
+	return append(ciphertext, tag...), nil
+}
+
+func (m *EAXMode) Open(cipher interfaces.BlockCipher, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	if err := m.check(cipher); err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < m.tagSize {
+		return nil, fmt.Errorf("ciphertext too short for EAX tag")
+	}
+	data := ciphertext[:len(ciphertext)-m.tagSize]
+	receivedTag := ciphertext[len(ciphertext)-m.tagSize:]
+
+	nonceMAC, err := omacTweaked(cipher, 0, nonce)
+	if err != nil {
+		return nil, err
+	}
+	expectedTag, err := m.tag(cipher, nonceMAC, data, additionalData)
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare(expectedTag, receivedTag) != 1 {
+		return nil, ErrAuthenticationFailed
+	}
+	return NewCTRMode(nonceMAC).Decrypt(cipher, data)
+}
+
+func (m *EAXMode) check(cipher interfaces.BlockCipher) error {
+	if m.tagSize > cipher.BlockSize() {
+		return fmt.Errorf("EAX tag size %d exceeds block size %d", m.tagSize, cipher.BlockSize())
+	}
+	return nil
+}
+
+func (m *EAXMode) tag(cipher interfaces.BlockCipher, nonceMAC, ciphertext, additionalData []byte) ([]byte, error) {
+	headerMAC, err := omacTweaked(cipher, 1, additionalData)
+	if err != nil {
+		return nil, err
+	}
+	ciphertextMAC, err := omacTweaked(cipher, 2, ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	tag := make([]byte, m.tagSize)
+	for i := range tag {
+		tag[i] = nonceMAC[i] ^ headerMAC[i] ^ ciphertextMAC[i]
+	}
+	return tag, nil
+}
+
+// omacTweaked считает OMAC над блоком [t]n, за которым идут данные
+func omacTweaked(cipher interfaces.BlockCipher, t byte, data []byte) ([]byte, error) {
+	blockSize := cipher.BlockSize()
+	message := make([]byte, blockSize, blockSize+len(data))
+	message[blockSize-1] = t
+	return cmac.MAC(cipher, append(message, data...))
+}
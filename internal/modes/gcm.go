@@ -0,0 +1,198 @@
+package modes
+
+import (
+	"crypto/subtle"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/Qwental/crypota/internal/gfield"
+	"github.com/Qwental/crypota/internal/interfaces"
+)
+
+const (
+	GCMBlockSize     = 16
+	GCMTagSize       = 16
+	GCMStandardNonce = 12
+)
+
+// GCMMode Galois/Counter Mode (NIST SP 800-38D): CTR-шифрование плюс GHASH-тег
+// над ассоциированными данными и шифртекстом. Encrypt/Decrypt используют nonce
+// и ассоциированные данные, заданные при создании, а шифртекст идет вместе с тегом
+type GCMMode struct {
+	nonce          []byte
+	additionalData []byte
+}
+
+func NewGCMMode(nonce, additionalData []byte) *GCMMode {
+	return &GCMMode{nonce: nonce, additionalData: additionalData}
+}
+
+func (m *GCMMode) Encrypt(cipher interfaces.BlockCipher, plaintext []byte) ([]byte, error) {
+	return m.Seal(cipher, m.nonce, plaintext, m.additionalData)
+}
+
+func (m *GCMMode) Decrypt(cipher interfaces.BlockCipher, ciphertext []byte) ([]byte, error) {
+	return m.Open(cipher, m.nonce, ciphertext, m.additionalData)
+}
+
+// Seal шифрует plaintext и возвращает шифртекст с дописанным в конец тегом
+func (m *GCMMode) Seal(cipher interfaces.BlockCipher, nonce, plaintext, additionalData []byte) ([]byte, error) {
+	h, j0, err := gcmSetup(cipher, nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	counter := make([]byte, GCMBlockSize)
+	copy(counter, j0)
+	incrementCounter32(counter)
+
+	output := make([]byte, len(plaintext)+GCMTagSize)
+	ciphertext := output[:len(plaintext)]
+	if err := gcmCTR(cipher, counter, ciphertext, plaintext); err != nil {
+		return nil, err
+	}
+
+	tag, err := gcmTag(cipher, h, j0, additionalData, ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	copy(output[len(plaintext):], tag)
+	return output, nil
+}
+
+// Open проверяет тег и только после этого расшифровывает данные
+func (m *GCMMode) Open(cipher interfaces.BlockCipher, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(ciphertext) < GCMTagSize {
+		return nil, fmt.Errorf("ciphertext too short for GCM tag")
+	}
+	h, j0, err := gcmSetup(cipher, nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	data := ciphertext[:len(ciphertext)-GCMTagSize]
+	receivedTag := ciphertext[len(ciphertext)-GCMTagSize:]
+
+	expectedTag, err := gcmTag(cipher, h, j0, additionalData, data)
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare(expectedTag, receivedTag) != 1 {
+		return nil, ErrAuthenticationFailed
+	}
+
+	counter := make([]byte, GCMBlockSize)
+	copy(counter, j0)
+	incrementCounter32(counter)
+
+	plaintext := make([]byte, len(data))
+	if err := gcmCTR(cipher, counter, plaintext, data); err != nil {
+		return nil, err
+	}
+	return plaintext, nil
+}
+
+// gcmSetup вычисляет подключ хеширования H = E(0) и начальный счетчик J0
+func gcmSetup(cipher interfaces.BlockCipher, nonce []byte) (h, j0 []byte, err error) {
+	if cipher.BlockSize() != GCMBlockSize {
+		return nil, nil, fmt.Errorf("GCM requires %d-byte block cipher, got %d", GCMBlockSize, cipher.BlockSize())
+	}
+	if len(nonce) == 0 {
+		return nil, nil, fmt.Errorf("GCM nonce must not be empty")
+	}
+
+	h, err = cipher.EncryptBlock(make([]byte, GCMBlockSize))
+	if err != nil {
+		return nil, nil, fmt.Errorf("hash subkey generation failed: %w", err)
+	}
+
+	if len(nonce) == GCMStandardNonce {
+		j0 = make([]byte, GCMBlockSize)
+		copy(j0, nonce)
+		j0[GCMBlockSize-1] = 1
+		return h, j0, nil
+	}
+
+	// для nonce нестандартной длины J0 = GHASH(nonce || 0 || [len(nonce)]64)
+	j0, err = ghash(h, nil, nonce)
+	if err != nil {
+		return nil, nil, err
+	}
+	return h, j0, nil
+}
+
+func gcmTag(cipher interfaces.BlockCipher, h, j0, additionalData, ciphertext []byte) ([]byte, error) {
+	s, err := ghash(h, additionalData, ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	encryptedJ0, err := cipher.EncryptBlock(j0)
+	if err != nil {
+		return nil, fmt.Errorf("tag encryption failed: %w", err)
+	}
+	for i := range s {
+		s[i] ^= encryptedJ0[i]
+	}
+	return s[:GCMTagSize], nil
+}
+
+// ghash считает GHASH_H(A || 0^v || C || 0^u || [len(A)]64 || [len(C)]64)
+func ghash(h, additionalData, ciphertext []byte) ([]byte, error) {
+	y := make([]byte, GCMBlockSize)
+	var err error
+
+	absorb := func(data []byte) error {
+		for i := 0; i < len(data); i += GCMBlockSize {
+			end := i + GCMBlockSize
+			if end > len(data) {
+				end = len(data)
+			}
+			for j := i; j < end; j++ {
+				y[j-i] ^= data[j]
+			}
+			if y, err = gfield.MultiplyGF128(y, h); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := absorb(additionalData); err != nil {
+		return nil, err
+	}
+	if err := absorb(ciphertext); err != nil {
+		return nil, err
+	}
+
+	lengths := make([]byte, GCMBlockSize)
+	binary.BigEndian.PutUint64(lengths[:8], uint64(len(additionalData))*8)
+	binary.BigEndian.PutUint64(lengths[8:], uint64(len(ciphertext))*8)
+	if err := absorb(lengths); err != nil {
+		return nil, err
+	}
+	return y, nil
+}
+
+// gcmCTR - GCTR из стандарта: счетчик увеличивается только в младших 32 битах
+func gcmCTR(cipher interfaces.BlockCipher, counter, dst, src []byte) error {
+	for i := 0; i < len(src); i += GCMBlockSize {
+		keystream, err := cipher.EncryptBlock(counter)
+		if err != nil {
+			return fmt.Errorf("block counter encryption failed: %w", err)
+		}
+		end := i + GCMBlockSize
+		if end > len(src) {
+			end = len(src)
+		}
+		for j := i; j < end; j++ {
+			dst[j] = src[j] ^ keystream[j-i]
+		}
+		incrementCounter32(counter)
+	}
+	return nil
+}
+
+func incrementCounter32(counter []byte) {
+	tail := counter[len(counter)-4:]
+	binary.BigEndian.PutUint32(tail, binary.BigEndian.Uint32(tail)+1)
+}
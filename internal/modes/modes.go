@@ -2,9 +2,9 @@ package modes
 
 import (
 	"crypto/rand"
+	"errors"
 	"fmt"
 	"math/big"
-	"sync"
 
 	"github.com/Qwental/crypota/internal/interfaces"
 )
@@ -19,8 +19,19 @@ const (
 	OFB
 	CTR
 	RandomDelta
+	GCM
+	CCM
+	EAX
+	OCB
+	SIV
+	CBCCS1
+	CBCCS2
+	CBCCS3
 )
 
+// ErrAuthenticationFailed возвращают режимы с аутентификацией, если тег не сошелся
+var ErrAuthenticationFailed = errors.New("message authentication failed")
+
 type Mode interface {
 	Encrypt(cipher interfaces.BlockCipher, plaintext []byte) ([]byte, error)
 	Decrypt(cipher interfaces.BlockCipher, ciphertext []byte) ([]byte, error)
@@ -34,33 +45,11 @@ func (m *ECBMode) Encrypt(cipher interfaces.BlockCipher, plaintext []byte) ([]by
 		return nil, fmt.Errorf("plaintext length must be multiple of block size")
 	}
 	ciphertext := make([]byte, len(plaintext))
-	numBlocks := len(plaintext) / blockSize
-	var wg sync.WaitGroup
-	var firstErr error
-	var errOnce sync.Once
-
-	for i := 0; i < numBlocks; i++ {
-		wg.Add(1)
-		go func(blockIdx int) {
-			defer wg.Done()
-			offset := blockIdx * blockSize
-			block := make([]byte, blockSize)
-			copy(block, plaintext[offset:offset+blockSize])
-
-			encryptedBlock, err := cipher.EncryptBlock(block)
-			if err != nil {
-				errOnce.Do(func() {
-					firstErr = fmt.Errorf("block %d encryption failed: %w", blockIdx, err)
-				})
-				return
-			}
-			copy(ciphertext[offset:offset+blockSize], encryptedBlock)
-		}(i)
-	}
-	wg.Wait()
-
-	if firstErr != nil {
-		return nil, firstErr
+	err := parallelBlocks(len(plaintext)/blockSize, func(from, to int) error {
+		return cryptBlocks(cipher, ciphertext[from*blockSize:to*blockSize], plaintext[from*blockSize:to*blockSize], from, false)
+	})
+	if err != nil {
+		return nil, err
 	}
 	return ciphertext, nil
 }
@@ -71,33 +60,11 @@ func (m *ECBMode) Decrypt(cipher interfaces.BlockCipher, ciphertext []byte) ([]b
 		return nil, fmt.Errorf("ciphertext length must be multiple of block size")
 	}
 	plaintext := make([]byte, len(ciphertext))
-	numBlocks := len(ciphertext) / blockSize
-	var wg sync.WaitGroup
-	var firstErr error
-	var errOnce sync.Once
-
-	for i := 0; i < numBlocks; i++ {
-		wg.Add(1)
-		go func(blockIdx int) {
-			defer wg.Done()
-			offset := blockIdx * blockSize
-			block := make([]byte, blockSize)
-			copy(block, ciphertext[offset:offset+blockSize])
-
-			decryptedBlock, err := cipher.DecryptBlock(block)
-			if err != nil {
-				errOnce.Do(func() {
-					firstErr = fmt.Errorf("block %d decryption failed: %w", blockIdx, err)
-				})
-				return
-			}
-			copy(plaintext[offset:offset+blockSize], decryptedBlock)
-		}(i)
-	}
-	wg.Wait()
-
-	if firstErr != nil {
-		return nil, firstErr
+	err := parallelBlocks(len(ciphertext)/blockSize, func(from, to int) error {
+		return cryptBlocks(cipher, plaintext[from*blockSize:to*blockSize], ciphertext[from*blockSize:to*blockSize], from, true)
+	})
+	if err != nil {
+		return nil, err
 	}
 	return plaintext, nil
 }
@@ -137,6 +104,7 @@ func (m *CBCMode) Encrypt(cipher interfaces.BlockCipher, plaintext []byte) ([]by
 	return ciphertext, nil
 }
 
+// Decrypt распараллеливается: P_i = D(C_i) xor C_{i-1}, все C известны заранее
 func (m *CBCMode) Decrypt(cipher interfaces.BlockCipher, ciphertext []byte) ([]byte, error) {
 	blockSize := cipher.BlockSize()
 	if len(ciphertext)%blockSize != 0 {
@@ -146,19 +114,25 @@ func (m *CBCMode) Decrypt(cipher interfaces.BlockCipher, ciphertext []byte) ([]b
 		return nil, fmt.Errorf("IV length must equal block size")
 	}
 	plaintext := make([]byte, len(ciphertext))
-	prevBlock := make([]byte, blockSize)
-	copy(prevBlock, m.iv)
-	for i := 0; i < len(ciphertext); i += blockSize {
-		block := ciphertext[i : i+blockSize]
-		decryptedBlock, err := cipher.DecryptBlock(block)
-		if err != nil {
-			return nil, err
-		}
-		for j := 0; j < blockSize; j++ {
-			decryptedBlock[j] ^= prevBlock[j]
+	err := parallelBlocks(len(ciphertext)/blockSize, func(from, to int) error {
+		for i := from; i < to; i++ {
+			offset := i * blockSize
+			decryptedBlock, err := cipher.DecryptBlock(ciphertext[offset : offset+blockSize])
+			if err != nil {
+				return err
+			}
+			prevBlock := m.iv
+			if i > 0 {
+				prevBlock = ciphertext[offset-blockSize : offset]
+			}
+			for j := 0; j < blockSize; j++ {
+				plaintext[offset+j] = decryptedBlock[j] ^ prevBlock[j]
+			}
 		}
-		copy(plaintext[i:i+blockSize], decryptedBlock)
-		copy(prevBlock, block)
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 	return plaintext, nil
 }
@@ -186,44 +160,51 @@ func (m *CTRMode) process(cipher interfaces.BlockCipher, data []byte) ([]byte, e
 	}
 	output := make([]byte, len(data))
 	numBlocks := (len(data) + blockSize - 1) / blockSize
-	var wg sync.WaitGroup
-	var firstErr error
-	var errOnce sync.Once
-
-	for i := 0; i < numBlocks; i++ {
-		wg.Add(1)
-		go func(blockIdx int) {
-			defer wg.Done()
-
-			blockCounter := make([]byte, blockSize)
-			copy(blockCounter, m.iv)
-			incrementCounterBy(blockCounter, blockIdx)
-
-			encryptedCounter, err := cipher.EncryptBlock(blockCounter)
-			if err != nil {
-				errOnce.Do(func() {
-					firstErr = fmt.Errorf("block counter encryption failed: %w", err)
-				})
-				return
+	err := parallelBlocks(numBlocks, func(from, to int) error {
+		if from == to {
+			return nil
+		}
+		// счетчики шифруются пакетами по batchBlocks, чтобы BatchCipher брал их целиком,
+		// а память на гамму не росла с длиной сообщения
+		batch := min(to-from, batchBlocks)
+		counters := make([]byte, batch*blockSize)
+		keystream := make([]byte, len(counters))
+		counter := make([]byte, blockSize)
+		copy(counter, m.iv)
+		incrementCounterBy(counter, from)
+
+		for start := from; start < to; start += batch {
+			n := min(batch, to-start)
+			for i := 0; i < n; i++ {
+				copy(counters[i*blockSize:], counter)
+				incrementCounter(counter)
 			}
-
-			offset := blockIdx * blockSize
-			end := offset + blockSize
-			if end > len(data) {
-				end = len(data)
+			if err := cryptBlocks(cipher, keystream[:n*blockSize], counters[:n*blockSize], start, false); err != nil {
+				return fmt.Errorf("block counter encryption failed: %w", err)
 			}
 
+			offset := start * blockSize
+			end := min((start+n)*blockSize, len(data))
 			for j := offset; j < end; j++ {
-				output[j] = data[j] ^ encryptedCounter[j-offset]
+				output[j] = data[j] ^ keystream[j-offset]
 			}
-		}(i)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
-	wg.Wait()
+	return output, nil
+}
 
-	if firstErr != nil {
-		return nil, firstErr
+// incrementCounter увеличивает счетчик на единицу по модулю 2^n
+func incrementCounter(counter []byte) {
+	for i := len(counter) - 1; i >= 0; i-- {
+		counter[i]++
+		if counter[i] != 0 {
+			return
+		}
 	}
-	return output, nil
 }
 
 func incrementCounterBy(counter []byte, value int) {
@@ -310,18 +291,31 @@ func (m *PCBCMode) Decrypt(cipher interfaces.BlockCipher, ciphertext []byte) ([]
 }
 
 type CFBMode struct {
-	iv []byte
+	iv          []byte
+	segmentBits int
 }
 
 func NewCFBMode(iv []byte) *CFBMode {
 	return &CFBMode{iv: iv}
 }
 
+// NewCFBSegmentMode CFB-s: обратная связь по s битам (CFB-1, CFB-8, ...), 0 - полный блок
+func NewCFBSegmentMode(iv []byte, segmentBits int) *CFBMode {
+	return &CFBMode{iv: iv, segmentBits: segmentBits}
+}
+
 func (m *CFBMode) Encrypt(cipher interfaces.BlockCipher, plaintext []byte) ([]byte, error) {
 	blockSize := cipher.BlockSize()
 	if len(m.iv) != blockSize {
 		return nil, fmt.Errorf("IV length must equal block size")
 	}
+	partial, err := isPartialSegment(m.segmentBits, blockSize)
+	if err != nil {
+		return nil, err
+	}
+	if partial {
+		return processSegments(cipher, m.iv, plaintext, m.segmentBits, feedbackOutput)
+	}
 	ciphertext := make([]byte, len(plaintext))
 	prevBlock := make([]byte, blockSize)
 	copy(prevBlock, m.iv)
@@ -352,39 +346,57 @@ func (m *CFBMode) Decrypt(cipher interfaces.BlockCipher, ciphertext []byte) ([]b
 	if len(m.iv) != blockSize {
 		return nil, fmt.Errorf("IV length must equal block size")
 	}
+	partial, err := isPartialSegment(m.segmentBits, blockSize)
+	if err != nil {
+		return nil, err
+	}
+	if partial {
+		return processSegments(cipher, m.iv, ciphertext, m.segmentBits, feedbackInput)
+	}
+	// как и в CBC, вход шифра для каждого блока - предыдущий блок шифртекста
 	plaintext := make([]byte, len(ciphertext))
-	prevBlock := make([]byte, blockSize)
-	copy(prevBlock, m.iv)
-	for i := 0; i < len(ciphertext); i += blockSize {
-		encryptedBlock, err := cipher.EncryptBlock(prevBlock)
-		if err != nil {
-			return nil, err
-		}
-		end := i + blockSize
-		if end > len(ciphertext) {
-			end = len(ciphertext)
-		}
-		currentSize := end - i
-		for j := 0; j < currentSize; j++ {
-			plaintext[i+j] = ciphertext[i+j] ^ encryptedBlock[j]
-		}
-		if currentSize == blockSize {
-			copy(prevBlock, ciphertext[i:i+blockSize])
-		} else {
-			copy(prevBlock, ciphertext[i:end])
+	numBlocks := (len(ciphertext) + blockSize - 1) / blockSize
+	err = parallelBlocks(numBlocks, func(from, to int) error {
+		for i := from; i < to; i++ {
+			offset := i * blockSize
+			prevBlock := m.iv
+			if i > 0 {
+				prevBlock = ciphertext[offset-blockSize : offset]
+			}
+			encryptedBlock, err := cipher.EncryptBlock(prevBlock)
+			if err != nil {
+				return err
+			}
+			end := offset + blockSize
+			if end > len(ciphertext) {
+				end = len(ciphertext)
+			}
+			for j := offset; j < end; j++ {
+				plaintext[j] = ciphertext[j] ^ encryptedBlock[j-offset]
+			}
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 	return plaintext, nil
 }
 
 type OFBMode struct {
-	iv []byte
+	iv          []byte
+	segmentBits int
 }
 
 func NewOFBMode(iv []byte) *OFBMode {
 	return &OFBMode{iv: iv}
 }
 
+// NewOFBSegmentMode OFB-s: в регистр вдвигаются s старших бит гаммы, 0 - полный блок
+func NewOFBSegmentMode(iv []byte, segmentBits int) *OFBMode {
+	return &OFBMode{iv: iv, segmentBits: segmentBits}
+}
+
 func (m *OFBMode) Encrypt(cipher interfaces.BlockCipher, plaintext []byte) ([]byte, error) {
 	return m.process(cipher, plaintext)
 }
@@ -398,6 +410,13 @@ func (m *OFBMode) process(cipher interfaces.BlockCipher, data []byte) ([]byte, e
 	if len(m.iv) != blockSize {
 		return nil, fmt.Errorf("IV length must equal block size")
 	}
+	partial, err := isPartialSegment(m.segmentBits, blockSize)
+	if err != nil {
+		return nil, err
+	}
+	if partial {
+		return processSegments(cipher, m.iv, data, m.segmentBits, feedbackKeystream)
+	}
 	output := make([]byte, len(data))
 	feedback := make([]byte, blockSize)
 	copy(feedback, m.iv)
@@ -419,19 +438,95 @@ func (m *OFBMode) process(cipher interfaces.BlockCipher, data []byte) ([]byte, e
 }
 
 type RandomDeltaMode struct {
-	iv []byte
+	iv    []byte
+	delta []byte
 }
 
 func NewRandomDeltaMode(iv []byte) *RandomDeltaMode {
-	return &RandomDeltaMode{iv: iv}
+	return &RandomDeltaMode{iv: iv, delta: deriveDelta(iv)}
 }
 
 func (m *RandomDeltaMode) Encrypt(cipher interfaces.BlockCipher, plaintext []byte) ([]byte, error) {
-	return nil, fmt.Errorf("RandomDelta encryption not implemented")
+	blockSize := cipher.BlockSize()
+	if len(plaintext)%blockSize != 0 {
+		return nil, fmt.Errorf("plaintext length must be multiple of block size")
+	}
+	if len(m.iv) != blockSize {
+		return nil, fmt.Errorf("IV length must equal block size")
+	}
+	ciphertext := make([]byte, len(plaintext))
+	current := make([]byte, blockSize)
+	copy(current, m.iv)
+	for i := 0; i < len(plaintext); i += blockSize {
+		block := make([]byte, blockSize)
+		copy(block, plaintext[i:i+blockSize])
+		for j := 0; j < blockSize; j++ {
+			block[j] ^= current[j]
+		}
+		encryptedBlock, err := cipher.EncryptBlock(block)
+		if err != nil {
+			return nil, err
+		}
+		for j := 0; j < blockSize; j++ {
+			ciphertext[i+j] = encryptedBlock[j] ^ current[j]
+		}
+		addDelta(current, m.delta)
+	}
+	return ciphertext, nil
 }
 
 func (m *RandomDeltaMode) Decrypt(cipher interfaces.BlockCipher, ciphertext []byte) ([]byte, error) {
-	return nil, fmt.Errorf("RandomDelta decryption not implemented")
+	blockSize := cipher.BlockSize()
+	if len(ciphertext)%blockSize != 0 {
+		return nil, fmt.Errorf("ciphertext length must be multiple of block size")
+	}
+	if len(m.iv) != blockSize {
+		return nil, fmt.Errorf("IV length must equal block size")
+	}
+	plaintext := make([]byte, len(ciphertext))
+	current := make([]byte, blockSize)
+	copy(current, m.iv)
+	for i := 0; i < len(ciphertext); i += blockSize {
+		block := make([]byte, blockSize)
+		copy(block, ciphertext[i:i+blockSize])
+		for j := 0; j < blockSize; j++ {
+			block[j] ^= current[j]
+		}
+		decryptedBlock, err := cipher.DecryptBlock(block)
+		if err != nil {
+			return nil, err
+		}
+		for j := 0; j < blockSize; j++ {
+			plaintext[i+j] = decryptedBlock[j] ^ current[j]
+		}
+		addDelta(current, m.delta)
+	}
+	return plaintext, nil
+}
+
+// deriveDelta строит шаг delta из IV: младшая половина IV, размноженная на весь блок,
+// с принудительно нечетным младшим байтом, чтобы последовательность IV + i*delta не зацикливалась раньше 2^n
+func deriveDelta(iv []byte) []byte {
+	if len(iv) < 2 {
+		return make([]byte, len(iv))
+	}
+	half := iv[len(iv)/2:]
+	delta := make([]byte, len(iv))
+	for i := range delta {
+		delta[i] = half[i%len(half)]
+	}
+	delta[len(delta)-1] |= 0x01
+	return delta
+}
+
+// addDelta прибавляет delta к value по модулю 2^(8*len(value)) (big-endian)
+func addDelta(value, delta []byte) {
+	var carry uint16
+	for i := len(value) - 1; i >= 0; i-- {
+		sum := uint16(value[i]) + uint16(delta[i]) + carry
+		value[i] = byte(sum)
+		carry = sum >> 8
+	}
 }
 
 func GenerateIV(size int) ([]byte, error) {
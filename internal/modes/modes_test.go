@@ -1 +1,1274 @@
 package modes
+
+import (
+	"bytes"
+	"crypto/aes"
+	stdcipher "crypto/cipher"
+	stddes "crypto/des"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/Qwental/crypota/internal/deal"
+	"github.com/Qwental/crypota/internal/des"
+	"github.com/Qwental/crypota/internal/interfaces"
+	"github.com/Qwental/crypota/internal/padding"
+	"github.com/Qwental/crypota/internal/rijndael"
+	"github.com/Qwental/crypota/internal/stdblock"
+)
+
+// newAESTestCipher - AES из стандартной библиотеки для сверки режимов
+func newAESTestCipher(t *testing.T, key []byte) *stdblock.BlockCipher {
+	t.Helper()
+	c, err := stdblock.NewBlockCipherFunc(aes.NewCipher, key)
+	if err != nil {
+		t.Fatalf("aes.NewCipher failed: %v", err)
+	}
+	return c
+}
+
+func newTestCiphers(t *testing.T) map[string]interfaces.BlockCipher {
+	t.Helper()
+
+	desCipher := des.NewDESCipher()
+	if err := desCipher.SetKey([]byte{0x13, 0x34, 0x57, 0x79, 0x9B, 0xBC, 0xDF, 0xF1}); err != nil {
+		t.Fatalf("DES SetKey failed: %v", err)
+	}
+
+	dealCipher, err := deal.NewDEALCipher(16)
+	if err != nil {
+		t.Fatalf("NewDEALCipher failed: %v", err)
+	}
+	if err := dealCipher.SetKey([]byte("0123456789abcdef")); err != nil {
+		t.Fatalf("DEAL SetKey failed: %v", err)
+	}
+
+	rijndaelCipher, err := rijndael.NewRijndaelCipher(16, 16, 0x1B)
+	if err != nil {
+		t.Fatalf("NewRijndaelCipher failed: %v", err)
+	}
+	if err := rijndaelCipher.SetKey([]byte("fedcba9876543210")); err != nil {
+		t.Fatalf("Rijndael SetKey failed: %v", err)
+	}
+
+	return map[string]interfaces.BlockCipher{
+		"DES":      desCipher,
+		"DEAL":     dealCipher,
+		"Rijndael": rijndaelCipher,
+	}
+}
+
+// TestRandomDeltaKnownAnswer сверяет RandomDelta с ручным расчетом поверх crypto/des
+func TestRandomDeltaKnownAnswer(t *testing.T) {
+	key, _ := hex.DecodeString("133457799BBCDFF1")
+	iv, _ := hex.DecodeString("0123456789ABCDEF")
+	plaintext, _ := hex.DecodeString("00112233445566778899AABBCCDDEEFF0011223344556677")
+
+	stdCipher, err := stddes.NewCipher(key)
+	if err != nil {
+		t.Fatalf("Standard DES NewCipher failed: %v", err)
+	}
+
+	// delta = 89ABCDEF89ABCDEF (младшая половина IV, младший бит уже 1)
+	deltas := []string{"0123456789ABCDEF", "8ACF135713579BDE", "147AE1469D0369CD"}
+	expected := make([]byte, len(plaintext))
+	for i, d := range deltas {
+		current, _ := hex.DecodeString(d)
+		block := make([]byte, 8)
+		for j := range block {
+			block[j] = plaintext[i*8+j] ^ current[j]
+		}
+		stdCipher.Encrypt(block, block)
+		for j := range block {
+			expected[i*8+j] = block[j] ^ current[j]
+		}
+	}
+
+	cipher := des.NewDESCipher()
+	if err := cipher.SetKey(key); err != nil {
+		t.Fatalf("SetKey failed: %v", err)
+	}
+	mode := NewRandomDeltaMode(iv)
+
+	ciphertext, err := mode.Encrypt(cipher, plaintext)
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+	if !bytes.Equal(ciphertext, expected) {
+		t.Errorf("Ciphertext mismatch\nExpected: %x\nGot:      %x", expected, ciphertext)
+	}
+
+	decrypted, err := mode.Decrypt(cipher, ciphertext)
+	if err != nil {
+		t.Fatalf("Decrypt failed: %v", err)
+	}
+	if !bytes.Equal(decrypted, plaintext) {
+		t.Errorf("Decryption failed\nExpected: %x\nGot:      %x", plaintext, decrypted)
+	}
+}
+
+func TestRandomDeltaRoundTrip(t *testing.T) {
+	for name, cipher := range newTestCiphers(t) {
+		t.Run(name, func(t *testing.T) {
+			blockSize := cipher.BlockSize()
+			iv, err := GenerateIV(blockSize)
+			if err != nil {
+				t.Fatalf("GenerateIV failed: %v", err)
+			}
+			plaintext := make([]byte, blockSize*7)
+			rand.Read(plaintext)
+
+			mode := NewRandomDeltaMode(iv)
+			ciphertext, err := mode.Encrypt(cipher, plaintext)
+			if err != nil {
+				t.Fatalf("Encrypt failed: %v", err)
+			}
+
+			decrypted, err := mode.Decrypt(cipher, ciphertext)
+			if err != nil {
+				t.Fatalf("Decrypt failed: %v", err)
+			}
+			if !bytes.Equal(decrypted, plaintext) {
+				t.Errorf("Round-trip failed")
+			}
+		})
+	}
+}
+
+func TestRandomDeltaIdenticalBlocksDiffer(t *testing.T) {
+	cipher := newTestCiphers(t)["DES"]
+	iv := make([]byte, 8)
+	plaintext := bytes.Repeat([]byte("AAAAAAAA"), 4)
+
+	ciphertext, err := NewRandomDeltaMode(iv).Encrypt(cipher, plaintext)
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+	for i := 8; i < len(ciphertext); i += 8 {
+		if bytes.Equal(ciphertext[i:i+8], ciphertext[i-8:i]) {
+			t.Errorf("Blocks %d and %d are equal, delta is not applied", i/8-1, i/8)
+		}
+	}
+}
+
+func TestRandomDeltaInvalidInput(t *testing.T) {
+	cipher := newTestCiphers(t)["DES"]
+
+	if _, err := NewRandomDeltaMode(make([]byte, 8)).Encrypt(cipher, make([]byte, 7)); err == nil {
+		t.Error("Expected error for partial block")
+	}
+	if _, err := NewRandomDeltaMode(make([]byte, 4)).Decrypt(cipher, make([]byte, 8)); err == nil {
+		t.Error("Expected error for short IV")
+	}
+}
+
+func TestGCMAgainstStandardLibrary(t *testing.T) {
+	key, _ := hex.DecodeString("feffe9928665731c6d6a8f9467308308")
+	cipher := newAESTestCipher(t, key)
+	block, _ := aes.NewCipher(key)
+
+	for _, nonceSize := range []int{12, 8, 16} {
+		for _, size := range []int{0, 1, 16, 60, 100} {
+			nonce := make([]byte, nonceSize)
+			rand.Read(nonce)
+			plaintext := make([]byte, size)
+			rand.Read(plaintext)
+			aad := []byte("feedfacedeadbeeffeedfacedeadbeefabaddad2")
+
+			stdGCM, err := stdcipher.NewGCMWithNonceSize(block, nonceSize)
+			if err != nil {
+				t.Fatalf("NewGCMWithNonceSize failed: %v", err)
+			}
+			expected := stdGCM.Seal(nil, nonce, plaintext, aad)
+
+			mode := NewGCMMode(nonce, aad)
+			sealed, err := mode.Encrypt(cipher, plaintext)
+			if err != nil {
+				t.Fatalf("Encrypt failed: %v", err)
+			}
+			if !bytes.Equal(sealed, expected) {
+				t.Fatalf("nonce %d, size %d: mismatch\nExpected: %x\nGot:      %x", nonceSize, size, expected, sealed)
+			}
+
+			opened, err := mode.Decrypt(cipher, sealed)
+			if err != nil {
+				t.Fatalf("Decrypt failed: %v", err)
+			}
+			if !bytes.Equal(opened, plaintext) {
+				t.Errorf("nonce %d, size %d: round-trip failed", nonceSize, size)
+			}
+		}
+	}
+}
+
+func TestGCMDetectsTampering(t *testing.T) {
+	for _, name := range []string{"DEAL", "Rijndael"} {
+		t.Run(name, func(t *testing.T) {
+			cipher := newTestCiphers(t)[name]
+			nonce := make([]byte, GCMStandardNonce)
+			rand.Read(nonce)
+			mode := NewGCMMode(nonce, []byte("header"))
+
+			sealed, err := mode.Seal(cipher, nonce, []byte("attack at dawn"), []byte("header"))
+			if err != nil {
+				t.Fatalf("Seal failed: %v", err)
+			}
+
+			opened, err := mode.Open(cipher, nonce, sealed, []byte("header"))
+			if err != nil || !bytes.Equal(opened, []byte("attack at dawn")) {
+				t.Fatalf("Open failed: %v", err)
+			}
+
+			tampered := append([]byte(nil), sealed...)
+			tampered[0] ^= 0x01
+			if _, err := mode.Open(cipher, nonce, tampered, []byte("header")); !errors.Is(err, ErrAuthenticationFailed) {
+				t.Errorf("Expected ErrAuthenticationFailed for tampered ciphertext, got %v", err)
+			}
+			if _, err := mode.Open(cipher, nonce, sealed, []byte("other")); !errors.Is(err, ErrAuthenticationFailed) {
+				t.Errorf("Expected ErrAuthenticationFailed for wrong associated data, got %v", err)
+			}
+		})
+	}
+}
+
+func TestGCMRejects64BitBlock(t *testing.T) {
+	cipher := newTestCiphers(t)["DES"]
+	if _, err := NewGCMMode(make([]byte, 12), nil).Encrypt(cipher, []byte("data")); err == nil {
+		t.Error("Expected error for 8-byte block cipher")
+	}
+}
+
+// TestCCMKnownAnswer примеры C.1-C.3 из NIST SP 800-38C
+func TestCCMKnownAnswer(t *testing.T) {
+	key, _ := hex.DecodeString("404142434445464748494a4b4c4d4e4f")
+	cipher := newAESTestCipher(t, key)
+
+	tests := []struct {
+		name       string
+		nonce      string
+		aad        string
+		plaintext  string
+		tagSize    int
+		ciphertext string
+	}{
+		{"C.1", "10111213141516", "0001020304050607", "20212223", 4, "7162015b4dac255d"},
+		{"C.2", "1011121314151617", "000102030405060708090a0b0c0d0e0f", "202122232425262728292a2b2c2d2e2f", 6, "d2a1f0e051ea5f62081a7792073d593d1fc64fbfaccd"},
+		{"C.3", "101112131415161718191a1b", "000102030405060708090a0b0c0d0e0f10111213", "202122232425262728292a2b2c2d2e2f3031323334353637", 8, "e3b201a9f5b71a7a9b1ceaeccd97e70b6176aad9a4428aa5484392fbc1b09951"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nonce, _ := hex.DecodeString(tt.nonce)
+			aad, _ := hex.DecodeString(tt.aad)
+			plaintext, _ := hex.DecodeString(tt.plaintext)
+			expected, _ := hex.DecodeString(tt.ciphertext)
+
+			mode, err := NewCCMMode(nonce, tt.tagSize, aad)
+			if err != nil {
+				t.Fatalf("NewCCMMode failed: %v", err)
+			}
+			sealed, err := mode.Encrypt(cipher, plaintext)
+			if err != nil {
+				t.Fatalf("Encrypt failed: %v", err)
+			}
+			if !bytes.Equal(sealed, expected) {
+				t.Fatalf("Mismatch\nExpected: %x\nGot:      %x", expected, sealed)
+			}
+
+			opened, err := mode.Decrypt(cipher, sealed)
+			if err != nil {
+				t.Fatalf("Decrypt failed: %v", err)
+			}
+			if !bytes.Equal(opened, plaintext) {
+				t.Errorf("Round-trip failed")
+			}
+		})
+	}
+}
+
+func TestCCMParametersAndTampering(t *testing.T) {
+	cipher := newTestCiphers(t)["DEAL"]
+	plaintext := []byte("sensor reading: 23.5C")
+
+	for _, nonceSize := range []int{7, 10, 13} {
+		for _, tagSize := range []int{4, 8, 16} {
+			nonce := make([]byte, nonceSize)
+			rand.Read(nonce)
+			mode, err := NewCCMMode(nonce, tagSize, []byte("device-42"))
+			if err != nil {
+				t.Fatalf("NewCCMMode failed: %v", err)
+			}
+
+			sealed, err := mode.Encrypt(cipher, plaintext)
+			if err != nil {
+				t.Fatalf("Encrypt failed: %v", err)
+			}
+			if len(sealed) != len(plaintext)+tagSize {
+				t.Errorf("nonce %d, tag %d: unexpected length %d", nonceSize, tagSize, len(sealed))
+			}
+
+			sealed[len(sealed)/2] ^= 0x80
+			opened, err := mode.Decrypt(cipher, sealed)
+			if !errors.Is(err, ErrAuthenticationFailed) {
+				t.Errorf("nonce %d, tag %d: expected ErrAuthenticationFailed, got %v", nonceSize, tagSize, err)
+			}
+			if opened != nil {
+				t.Errorf("nonce %d, tag %d: plaintext returned on failed authentication", nonceSize, tagSize)
+			}
+		}
+	}
+
+	for _, tagSize := range []int{2, 5, 18} {
+		if _, err := NewCCMMode(make([]byte, 12), tagSize, nil); err == nil {
+			t.Errorf("Expected error for tag size %d", tagSize)
+		}
+	}
+	unbound, _ := NewCCMMode(nil, 16, nil)
+	for _, nonceSize := range []int{6, 14} {
+		if _, err := NewCCMMode(make([]byte, nonceSize), 16, nil); err == nil {
+			t.Errorf("Expected constructor error for nonce size %d", nonceSize)
+		}
+		if _, err := unbound.Seal(cipher, make([]byte, nonceSize), plaintext, nil); err == nil {
+			t.Errorf("Expected Seal error for nonce size %d", nonceSize)
+		}
+	}
+}
+
+// TestEAXKnownAnswer тест-векторы из статьи EAX (AES-128)
+func TestEAXKnownAnswer(t *testing.T) {
+	tests := []struct {
+		key, nonce, header, plaintext, ciphertext string
+	}{
+		{"233952DEE4D5ED5F9B9C6D6FF80FF478", "62EC67F9C3A4A407FCB2A8C49031A8B3", "6BFB914FD07EAE6B", "", "E037830E8389F27B025A2D6527E79D01"},
+		{"91945D3F4DCBEE0BF45EF52255F095A4", "BECAF043B0A23D843194BA972C66DEBD", "FA3BFD4806EB53FA", "F7FB", "19DD5C4C9331049D0BDAB0277408F67967E5"},
+		{"01F74AD64077F2E704C0F60ADA3DD523", "70C3DB4F0D26368400A10ED05D2BFF5E", "234A3463C1264AC6", "1A47CB4933", "D851D5BAE03A59F238A23E39199DC9266626C40F80"},
+	}
+
+	for _, tt := range tests {
+		key, _ := hex.DecodeString(tt.key)
+		nonce, _ := hex.DecodeString(tt.nonce)
+		header, _ := hex.DecodeString(tt.header)
+		plaintext, _ := hex.DecodeString(tt.plaintext)
+		expected, _ := hex.DecodeString(tt.ciphertext)
+		cipher := newAESTestCipher(t, key)
+
+		mode, err := NewEAXMode(nonce, 16, header)
+		if err != nil {
+			t.Fatalf("NewEAXMode failed: %v", err)
+		}
+		sealed, err := mode.Encrypt(cipher, plaintext)
+		if err != nil {
+			t.Fatalf("Encrypt failed: %v", err)
+		}
+		if !bytes.Equal(sealed, expected) {
+			t.Errorf("Mismatch\nExpected: %x\nGot:      %x", expected, sealed)
+		}
+		opened, err := mode.Decrypt(cipher, sealed)
+		if err != nil || !bytes.Equal(opened, plaintext) {
+			t.Errorf("Round-trip failed: %v", err)
+		}
+	}
+}
+
+// TestOCBKnownAnswer примеры из приложения A RFC 7253
+func TestOCBKnownAnswer(t *testing.T) {
+	key, _ := hex.DecodeString("000102030405060708090A0B0C0D0E0F")
+	cipher := newAESTestCipher(t, key)
+
+	tests := []struct {
+		nonce, aad, plaintext, ciphertext string
+	}{
+		{"BBAA99887766554433221100", "", "", "785407BFFFC8AD9EDCC5520AC9111EE6"},
+		{"BBAA99887766554433221101", "0001020304050607", "0001020304050607", "6820B3657B6F615A5725BDA0D3B4EB3A257C9AF1F8F03009"},
+		{"BBAA99887766554433221102", "0001020304050607", "", "81017F8203F081277152FADE694A0A00"},
+		{"BBAA99887766554433221103", "", "0001020304050607", "45DD69F8F5AAE72414054CD1F35D82760B2CD00D2F99BFA9"},
+		{"BBAA99887766554433221104", "000102030405060708090A0B0C0D0E0F", "000102030405060708090A0B0C0D0E0F", "571D535B60B277188BE5147170A9A22C3AD7A4FF3835B8C5701C1CCEC8FC3358"},
+	}
+
+	for _, tt := range tests {
+		nonce, _ := hex.DecodeString(tt.nonce)
+		aad, _ := hex.DecodeString(tt.aad)
+		plaintext, _ := hex.DecodeString(tt.plaintext)
+		expected, _ := hex.DecodeString(tt.ciphertext)
+
+		mode, err := NewOCBMode(nonce, 16, aad)
+		if err != nil {
+			t.Fatalf("NewOCBMode failed: %v", err)
+		}
+		sealed, err := mode.Encrypt(cipher, plaintext)
+		if err != nil {
+			t.Fatalf("Encrypt failed: %v", err)
+		}
+		if !bytes.Equal(sealed, expected) {
+			t.Errorf("Nonce %s: mismatch\nExpected: %x\nGot:      %x", tt.nonce, expected, sealed)
+		}
+		opened, err := mode.Decrypt(cipher, sealed)
+		if err != nil || !bytes.Equal(opened, plaintext) {
+			t.Errorf("Nonce %s: round-trip failed: %v", tt.nonce, err)
+		}
+	}
+}
+
+func TestAEADModesRoundTripAndTampering(t *testing.T) {
+	ciphers := newTestCiphers(t)
+	newModes := func(blockSize int) map[string]AEADMode {
+		result := map[string]AEADMode{}
+		eax, _ := NewEAXMode(nil, blockSize, nil)
+		result["EAX"] = eax
+		if blockSize == 16 {
+			ocb, _ := NewOCBMode(nil, 16, nil)
+			ccm, _ := NewCCMMode(nil, 16, nil)
+			result["OCB"] = ocb
+			result["CCM"] = ccm
+			result["GCM"] = NewGCMMode(nil, nil)
+		}
+		return result
+	}
+
+	for cipherName, cipher := range ciphers {
+		for modeName, mode := range newModes(cipher.BlockSize()) {
+			t.Run(cipherName+"-"+modeName, func(t *testing.T) {
+				nonce := make([]byte, 12)
+				rand.Read(nonce)
+				aad := []byte("associated data")
+
+				for _, size := range []int{0, 1, 15, 16, 33, 100} {
+					plaintext := make([]byte, size)
+					rand.Read(plaintext)
+
+					sealed, err := mode.Seal(cipher, nonce, plaintext, aad)
+					if err != nil {
+						t.Fatalf("Seal failed: %v", err)
+					}
+					if len(sealed) != size+mode.Overhead() {
+						t.Errorf("Unexpected sealed length %d for %d bytes", len(sealed), size)
+					}
+
+					opened, err := mode.Open(cipher, nonce, sealed, aad)
+					if err != nil {
+						t.Fatalf("Open failed: %v", err)
+					}
+					if !bytes.Equal(opened, plaintext) {
+						t.Fatalf("Round-trip failed for %d bytes", size)
+					}
+
+					tampered := append([]byte(nil), sealed...)
+					tampered[len(tampered)-1] ^= 0x01
+					if _, err := mode.Open(cipher, nonce, tampered, aad); !errors.Is(err, ErrAuthenticationFailed) {
+						t.Errorf("Expected ErrAuthenticationFailed, got %v", err)
+					}
+					if _, err := mode.Open(cipher, nonce, sealed, []byte("other")); !errors.Is(err, ErrAuthenticationFailed) {
+						t.Errorf("Expected ErrAuthenticationFailed for wrong associated data, got %v", err)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestOCBRejects64BitBlock(t *testing.T) {
+	mode, _ := NewOCBMode(make([]byte, 12), 16, nil)
+	if _, err := mode.Encrypt(newTestCiphers(t)["DES"], []byte("data")); err == nil {
+		t.Error("Expected error for 8-byte block cipher")
+	}
+}
+
+func BenchmarkAEADModes(b *testing.B) {
+	dealCipher, _ := deal.NewDEALCipher(16)
+	dealCipher.SetKey([]byte("0123456789abcdef"))
+	rijndaelCipher, _ := rijndael.NewRijndaelCipher(16, 16, 0x1B)
+	rijndaelCipher.SetKey([]byte("fedcba9876543210"))
+
+	ciphers := map[string]interfaces.BlockCipher{"DEAL": dealCipher, "Rijndael": rijndaelCipher}
+	eax, _ := NewEAXMode(nil, 16, nil)
+	ocb, _ := NewOCBMode(nil, 16, nil)
+	ccm, _ := NewCCMMode(nil, 16, nil)
+	aeadModes := map[string]AEADMode{"GCM": NewGCMMode(nil, nil), "CCM": ccm, "EAX": eax, "OCB": ocb}
+
+	nonce := make([]byte, 12)
+	plaintext := make([]byte, 1024)
+	aad := make([]byte, 64)
+
+	for cipherName, cipher := range ciphers {
+		for modeName, mode := range aeadModes {
+			b.Run(cipherName+"-"+modeName, func(b *testing.B) {
+				b.SetBytes(int64(len(plaintext)))
+				for i := 0; i < b.N; i++ {
+					if _, err := mode.Seal(cipher, nonce, plaintext, aad); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
+
+// TestSIVKnownAnswer примеры A.1 и A.2 из RFC 5297
+func TestSIVKnownAnswer(t *testing.T) {
+	tests := []struct {
+		name           string
+		key            string
+		associatedData []string
+		plaintext      string
+		ciphertext     string
+	}{
+		{
+			"A.1 deterministic",
+			"fffefdfcfbfaf9f8f7f6f5f4f3f2f1f0f0f1f2f3f4f5f6f7f8f9fafbfcfdfeff",
+			[]string{"101112131415161718191a1b1c1d1e1f2021222324252627"},
+			"112233445566778899aabbccddee",
+			"85632d07c6e8f37f950acd320a2ecc9340c02b9690c4dc04daef7f6afe5c",
+		},
+		{
+			"A.2 nonce-based",
+			"7f7e7d7c7b7a79787776757473727170404142434445464748494a4b4c4d4e4f",
+			[]string{
+				"00112233445566778899aabbccddeeffdeaddadadeaddadaffeeddccbbaa99887766554433221100",
+				"102030405060708090a0",
+				"09f911029d74e35bd84156c5635688c0",
+			},
+			"7468697320697320736f6d6520706c61696e7465787420746f20656e6372797074207573696e67205349562d414553",
+			"7bdb6e3b432667eb06f4d14bff2fbd0fcb900f2fddbe404326601965c889bf17dba77ceb094fa663b7a3f748ba8af829ea64ad544a272e9c485b62a3fd5c0d",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, _ := hex.DecodeString(tt.key)
+			plaintext, _ := hex.DecodeString(tt.plaintext)
+			expected, _ := hex.DecodeString(tt.ciphertext)
+			var associatedData [][]byte
+			for _, ad := range tt.associatedData {
+				decoded, _ := hex.DecodeString(ad)
+				associatedData = append(associatedData, decoded)
+			}
+
+			macCipher := newAESTestCipher(t, key[:16])
+			ctrCipher := newAESTestCipher(t, key[16:])
+			mode := NewSIVMode(macCipher, associatedData...)
+
+			sealed, err := mode.Encrypt(ctrCipher, plaintext)
+			if err != nil {
+				t.Fatalf("Encrypt failed: %v", err)
+			}
+			if !bytes.Equal(sealed, expected) {
+				t.Fatalf("Mismatch\nExpected: %x\nGot:      %x", expected, sealed)
+			}
+
+			opened, err := mode.Decrypt(ctrCipher, sealed)
+			if err != nil {
+				t.Fatalf("Decrypt failed: %v", err)
+			}
+			if !bytes.Equal(opened, plaintext) {
+				t.Errorf("Round-trip failed")
+			}
+
+			sealed[0] ^= 0x01
+			if _, err := mode.Decrypt(ctrCipher, sealed); !errors.Is(err, ErrAuthenticationFailed) {
+				t.Errorf("Expected ErrAuthenticationFailed, got %v", err)
+			}
+		})
+	}
+}
+
+func TestSIVDeterministicWithRijndael(t *testing.T) {
+	macCipher, _ := rijndael.NewRijndaelCipher(16, 16, 0x1B)
+	macCipher.SetKey([]byte("mac key 16 bytes"))
+	ctrCipher, _ := rijndael.NewRijndaelCipher(16, 16, 0x1B)
+	ctrCipher.SetKey([]byte("ctr key 16 bytes"))
+
+	mode := NewSIVMode(macCipher, []byte("config"), []byte("v1"))
+	blob := []byte(`{"listen": ":8080", "workers": 4}`)
+
+	first, err := mode.Encrypt(ctrCipher, blob)
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+	second, err := mode.Encrypt(ctrCipher, blob)
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+	if !bytes.Equal(first, second) {
+		t.Errorf("SIV must be deterministic for identical input")
+	}
+
+	other, err := NewSIVMode(macCipher, []byte("config"), []byte("v2")).Encrypt(ctrCipher, blob)
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+	if bytes.Equal(first[:SIVBlockSize], other[:SIVBlockSize]) {
+		t.Errorf("Different associated data must give different synthetic IV")
+	}
+	if _, err := NewSIVMode(macCipher, []byte("config"), []byte("v2")).Decrypt(ctrCipher, first); !errors.Is(err, ErrAuthenticationFailed) {
+		t.Errorf("Expected ErrAuthenticationFailed for wrong associated data, got %v", err)
+	}
+}
+
+// TestXTSKnownAnswer векторы 1, 2 и 15 из IEEE 1619-2007
+func TestXTSKnownAnswer(t *testing.T) {
+	tests := []struct {
+		name       string
+		key1, key2 string
+		sector     uint64
+		plaintext  string
+		ciphertext string
+	}{
+		{
+			"vector 1",
+			"00000000000000000000000000000000", "00000000000000000000000000000000", 0,
+			"0000000000000000000000000000000000000000000000000000000000000000",
+			"917cf69ebd68b2ec9b9fe9a3eadda692cd43d2f59598ed858c02c2652fbf922e",
+		},
+		{
+			"vector 2",
+			"11111111111111111111111111111111", "22222222222222222222222222222222", 0x3333333333,
+			"4444444444444444444444444444444444444444444444444444444444444444",
+			"c454185e6a16936e39334038acef838bfb186fff7480adc4289382ecd6d394f0",
+		},
+		{
+			"vector 15 ciphertext stealing",
+			"fffefdfcfbfaf9f8f7f6f5f4f3f2f1f0", "bfbebdbcbbbab9b8b7b6b5b4b3b2b1b0", 0x123456789a,
+			"000102030405060708090a0b0c0d0e0f10",
+			"6c1625db4671522d3d7599601de7ca09ed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key1, _ := hex.DecodeString(tt.key1)
+			key2, _ := hex.DecodeString(tt.key2)
+			plaintext, _ := hex.DecodeString(tt.plaintext)
+			expected, _ := hex.DecodeString(tt.ciphertext)
+
+			mode, err := NewXTSMode(newAESTestCipher(t, key1), newAESTestCipher(t, key2))
+			if err != nil {
+				t.Fatalf("NewXTSMode failed: %v", err)
+			}
+			ciphertext, err := mode.EncryptSector(tt.sector, plaintext)
+			if err != nil {
+				t.Fatalf("EncryptSector failed: %v", err)
+			}
+			if !bytes.Equal(ciphertext, expected) {
+				t.Fatalf("Mismatch\nExpected: %x\nGot:      %x", expected, ciphertext)
+			}
+			decrypted, err := mode.DecryptSector(tt.sector, ciphertext)
+			if err != nil {
+				t.Fatalf("DecryptSector failed: %v", err)
+			}
+			if !bytes.Equal(decrypted, plaintext) {
+				t.Errorf("Round-trip failed")
+			}
+		})
+	}
+}
+
+func TestXTSRoundTrip(t *testing.T) {
+	for _, name := range []string{"DEAL", "Rijndael"} {
+		t.Run(name, func(t *testing.T) {
+			dataCipher := newTestCiphers(t)[name]
+			tweakCipher := newTestCiphers(t)["Rijndael"]
+			mode, err := NewXTSMode(dataCipher, tweakCipher)
+			if err != nil {
+				t.Fatalf("NewXTSMode failed: %v", err)
+			}
+
+			for _, size := range []int{16, 17, 31, 32, 100, 512} {
+				plaintext := make([]byte, size)
+				rand.Read(plaintext)
+
+				ciphertext, err := mode.EncryptSector(7, plaintext)
+				if err != nil {
+					t.Fatalf("EncryptSector failed: %v", err)
+				}
+				if len(ciphertext) != size {
+					t.Errorf("Ciphertext length %d, want %d", len(ciphertext), size)
+				}
+				other, _ := mode.EncryptSector(8, plaintext)
+				if bytes.Equal(ciphertext, other) {
+					t.Errorf("Different sectors must give different ciphertext")
+				}
+
+				decrypted, err := mode.DecryptSector(7, ciphertext)
+				if err != nil {
+					t.Fatalf("DecryptSector failed: %v", err)
+				}
+				if !bytes.Equal(decrypted, plaintext) {
+					t.Errorf("Round-trip failed for %d bytes", size)
+				}
+			}
+
+			if _, err := mode.EncryptSector(0, make([]byte, 15)); err == nil {
+				t.Error("Expected error for data unit shorter than a block")
+			}
+		})
+	}
+
+	if _, err := NewXTSMode(newTestCiphers(t)["DES"], newTestCiphers(t)["DES"]); err == nil {
+		t.Error("Expected error for 8-byte block cipher")
+	}
+}
+
+// TestCBCCSKnownAnswer векторы AES CTS из RFC 3962 (Kerberos использует CS3), CS1 и CS2
+// получаются из того же шифртекста перестановкой двух последних блоков
+func TestCBCCSKnownAnswer(t *testing.T) {
+	key, _ := hex.DecodeString("636869636b656e207465726979616b69")
+	iv := make([]byte, 16)
+
+	tests := []struct {
+		plaintext string
+		cs3       string
+	}{
+		{
+			"4920776f756c64206c696b652074686520",
+			"c6353568f2bf8cb4d8a580362da7ff7f97",
+		},
+		{
+			"4920776f756c64206c696b65207468652047656e6572616c20476175277320",
+			"fc00783e0efdb2c1d445d4c8eff7ed2297687268d6ecccc0c07b25e25ecfe5",
+		},
+		{
+			"4920776f756c64206c696b65207468652047656e6572616c2047617527732043",
+			"39312523a78662d5be7fcbcc98ebf5a897687268d6ecccc0c07b25e25ecfe584",
+		},
+	}
+
+	for _, tt := range tests {
+		plaintext, _ := hex.DecodeString(tt.plaintext)
+		cs3, _ := hex.DecodeString(tt.cs3)
+
+		// в CS3 последним идет неполный блок C(n-1)*, перед ним - полный Cn
+		d := len(plaintext) - 16
+		cs1 := append(append([]byte(nil), cs3[16:16+d]...), cs3[:16]...)
+		cs2 := cs3
+		if d == 16 {
+			cs2 = cs1
+		}
+
+		for variant, expected := range map[StealingVariant][]byte{CS1: cs1, CS2: cs2, CS3: cs3} {
+			t.Run(fmt.Sprintf("CS%d-%d", variant, len(plaintext)), func(t *testing.T) {
+				cipher := newAESTestCipher(t, key)
+				mode := NewCBCCSMode(iv, variant)
+				ciphertext, err := mode.Encrypt(cipher, plaintext)
+				if err != nil {
+					t.Fatalf("Encrypt failed: %v", err)
+				}
+				if !bytes.Equal(ciphertext, expected) {
+					t.Fatalf("Mismatch\nExpected: %x\nGot:      %x", expected, ciphertext)
+				}
+				decrypted, err := mode.Decrypt(cipher, ciphertext)
+				if err != nil {
+					t.Fatalf("Decrypt failed: %v", err)
+				}
+				if !bytes.Equal(decrypted, plaintext) {
+					t.Errorf("Round-trip failed")
+				}
+			})
+		}
+	}
+}
+
+func TestCBCCSRoundTrip(t *testing.T) {
+	for name, cipher := range newTestCiphers(t) {
+		blockSize := cipher.BlockSize()
+		iv := make([]byte, blockSize)
+		rand.Read(iv)
+
+		for _, variant := range []StealingVariant{CS1, CS2, CS3} {
+			mode := NewCBCCSMode(iv, variant)
+			for _, size := range []int{blockSize, blockSize + 1, 2*blockSize - 1, 2 * blockSize, 5*blockSize + 3} {
+				t.Run(fmt.Sprintf("%s-CS%d-%d", name, variant, size), func(t *testing.T) {
+					plaintext := make([]byte, size)
+					rand.Read(plaintext)
+
+					ciphertext, err := mode.Encrypt(cipher, plaintext)
+					if err != nil {
+						t.Fatalf("Encrypt failed: %v", err)
+					}
+					if len(ciphertext) != size {
+						t.Errorf("Ciphertext length %d, want %d", len(ciphertext), size)
+					}
+					if size%blockSize == 0 && variant != CS3 {
+						expected, _ := NewCBCMode(iv).Encrypt(cipher, plaintext)
+						if !bytes.Equal(ciphertext, expected) {
+							t.Errorf("CS%d must equal plain CBC for block-aligned input", variant)
+						}
+					}
+
+					decrypted, err := mode.Decrypt(cipher, ciphertext)
+					if err != nil {
+						t.Fatalf("Decrypt failed: %v", err)
+					}
+					if !bytes.Equal(decrypted, plaintext) {
+						t.Errorf("Round-trip failed")
+					}
+				})
+			}
+
+			if _, err := mode.Encrypt(cipher, make([]byte, blockSize-1)); err == nil {
+				t.Errorf("%s: expected error for input shorter than a block", name)
+			}
+		}
+	}
+}
+
+// TestCFBSegmentKnownAnswer векторы CFB1-AES128 и CFB8-AES128 из NIST SP 800-38A (F.3.1, F.3.7)
+func TestCFBSegmentKnownAnswer(t *testing.T) {
+	key, _ := hex.DecodeString("2b7e151628aed2a6abf7158809cf4f3c")
+	iv, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+
+	tests := []struct {
+		segmentBits int
+		plaintext   string
+		ciphertext  string
+	}{
+		{1, "6bc1", "68b3"},
+		{8, "6bc1bee22e409f96e93d7e117393172aae2d", "3b79424c9c0dd436bace9e0ed4586a4f32b9"},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("CFB%d", tt.segmentBits), func(t *testing.T) {
+			cipher := newAESTestCipher(t, key)
+			plaintext, _ := hex.DecodeString(tt.plaintext)
+			expected, _ := hex.DecodeString(tt.ciphertext)
+
+			mode := NewCFBSegmentMode(iv, tt.segmentBits)
+			ciphertext, err := mode.Encrypt(cipher, plaintext)
+			if err != nil {
+				t.Fatalf("Encrypt failed: %v", err)
+			}
+			if !bytes.Equal(ciphertext, expected) {
+				t.Fatalf("Mismatch\nExpected: %x\nGot:      %x", expected, ciphertext)
+			}
+			decrypted, err := mode.Decrypt(cipher, ciphertext)
+			if err != nil {
+				t.Fatalf("Decrypt failed: %v", err)
+			}
+			if !bytes.Equal(decrypted, plaintext) {
+				t.Errorf("Round-trip failed")
+			}
+		})
+	}
+}
+
+func TestSegmentModesRoundTrip(t *testing.T) {
+	for name, cipher := range newTestCiphers(t) {
+		blockSize := cipher.BlockSize()
+		iv := make([]byte, blockSize)
+		rand.Read(iv)
+		plaintext := make([]byte, 3*blockSize+5)
+		rand.Read(plaintext)
+
+		full := map[string][2]Mode{
+			"CFB": {NewCFBMode(iv), NewCFBSegmentMode(iv, blockSize*8)},
+			"OFB": {NewOFBMode(iv), NewOFBSegmentMode(iv, blockSize*8)},
+		}
+		for modeName, pair := range full {
+			expected, _ := pair[0].Encrypt(cipher, plaintext)
+			got, err := pair[1].Encrypt(cipher, plaintext)
+			if err != nil {
+				t.Fatalf("%s %s: Encrypt failed: %v", name, modeName, err)
+			}
+			if !bytes.Equal(got, expected) {
+				t.Errorf("%s %s: full-block segment must match plain mode", name, modeName)
+			}
+		}
+
+		for _, s := range []int{1, 5, 8, 12, 24, blockSize*8 - 1} {
+			for modeName, mode := range map[string]Mode{
+				"CFB": NewCFBSegmentMode(iv, s),
+				"OFB": NewOFBSegmentMode(iv, s),
+			} {
+				t.Run(fmt.Sprintf("%s-%s-%d", name, modeName, s), func(t *testing.T) {
+					ciphertext, err := mode.Encrypt(cipher, plaintext)
+					if err != nil {
+						t.Fatalf("Encrypt failed: %v", err)
+					}
+					if len(ciphertext) != len(plaintext) {
+						t.Errorf("Ciphertext length %d, want %d", len(ciphertext), len(plaintext))
+					}
+					decrypted, err := mode.Decrypt(cipher, ciphertext)
+					if err != nil {
+						t.Fatalf("Decrypt failed: %v", err)
+					}
+					if !bytes.Equal(decrypted, plaintext) {
+						t.Errorf("Round-trip failed")
+					}
+				})
+			}
+		}
+
+		if _, err := NewCFBSegmentMode(iv, blockSize*8+1).Encrypt(cipher, plaintext); err == nil {
+			t.Errorf("%s: expected error for segment larger than block", name)
+		}
+	}
+}
+
+// useWorkerPool подменяет общий пул на время теста
+func useWorkerPool(tb testing.TB, workers int) {
+	tb.Helper()
+	saved := DefaultWorkerPool()
+	SetWorkerPool(NewWorkerPool(workers))
+	tb.Cleanup(func() { SetWorkerPool(saved) })
+}
+
+func TestWorkerPoolModesMatchSerial(t *testing.T) {
+	ciphers := newTestCiphers(t)
+	for _, name := range []string{"DES", "Rijndael"} {
+		cipher := ciphers[name]
+		blockSize := cipher.BlockSize()
+		iv := make([]byte, blockSize)
+		rand.Read(iv)
+		plaintext := make([]byte, 1000*blockSize)
+		rand.Read(plaintext)
+
+		tested := map[string]Mode{
+			"ECB": &ECBMode{},
+			"CBC": NewCBCMode(iv),
+			"CFB": NewCFBMode(iv),
+			"CTR": NewCTRMode(iv),
+		}
+		for modeName, mode := range tested {
+			t.Run(name+"-"+modeName, func(t *testing.T) {
+				useWorkerPool(t, 1)
+				expected, err := mode.Encrypt(cipher, plaintext)
+				if err != nil {
+					t.Fatalf("Encrypt failed: %v", err)
+				}
+
+				for _, workers := range []int{2, 3, 8} {
+					SetWorkerPool(NewWorkerPool(workers))
+					ciphertext, err := mode.Encrypt(cipher, plaintext)
+					if err != nil {
+						t.Fatalf("Encrypt failed: %v", err)
+					}
+					if !bytes.Equal(ciphertext, expected) {
+						t.Fatalf("%d workers: ciphertext differs from serial", workers)
+					}
+					decrypted, err := mode.Decrypt(cipher, ciphertext)
+					if err != nil {
+						t.Fatalf("Decrypt failed: %v", err)
+					}
+					if !bytes.Equal(decrypted, plaintext) {
+						t.Fatalf("%d workers: round-trip failed", workers)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestWorkerPoolReportsError(t *testing.T) {
+	useWorkerPool(t, 4)
+	cipher := newTestCiphers(t)["DES"]
+	if _, err := NewCTRMode(make([]byte, 16)).Encrypt(cipher, make([]byte, 8000)); err == nil {
+		t.Error("Expected error for wrong IV length")
+	}
+
+	failing := &failingCipher{BlockCipher: cipher, failAt: 700}
+	if _, err := (&ECBMode{}).Encrypt(failing, make([]byte, 8*1000)); err == nil {
+		t.Error("Expected error from a failing block in a worker")
+	}
+}
+
+// failingCipher отказывает на failAt-м вызове EncryptBlock
+type failingCipher struct {
+	interfaces.BlockCipher
+	calls  atomic.Int64
+	failAt int64
+}
+
+func (f *failingCipher) EncryptBlock(block []byte) ([]byte, error) {
+	if f.calls.Add(1) == f.failAt {
+		return nil, errors.New("injected failure")
+	}
+	return f.BlockCipher.EncryptBlock(block)
+}
+
+// ecbGoroutinePerBlock - прежняя реализация ECB (горутина на каждый блок) для сравнения в бенчмарке
+func ecbGoroutinePerBlock(cipher interfaces.BlockCipher, plaintext []byte) []byte {
+	blockSize := cipher.BlockSize()
+	ciphertext := make([]byte, len(plaintext))
+	var wg sync.WaitGroup
+	for i := 0; i < len(plaintext)/blockSize; i++ {
+		wg.Add(1)
+		go func(offset int) {
+			defer wg.Done()
+			block, _ := cipher.EncryptBlock(plaintext[offset : offset+blockSize])
+			copy(ciphertext[offset:], block)
+		}(i * blockSize)
+	}
+	wg.Wait()
+	return ciphertext
+}
+
+func BenchmarkParallelModes(b *testing.B) {
+	desCipher := des.NewDESCipher()
+	desCipher.SetKey([]byte{0x13, 0x34, 0x57, 0x79, 0x9B, 0xBC, 0xDF, 0xF1})
+	fastCipher := des.NewFastDESCipher()
+	fastCipher.SetKey([]byte{0x13, 0x34, 0x57, 0x79, 0x9B, 0xBC, 0xDF, 0xF1})
+	rijndaelCipher, _ := rijndael.NewRijndaelCipher(16, 16, 0x1B)
+	rijndaelCipher.SetKey(make([]byte, 16))
+
+	data := make([]byte, 64*1024)
+	rand.Read(data)
+
+	for _, c := range []struct {
+		name   string
+		cipher interfaces.BlockCipher
+	}{{"DES", desCipher}, {"DES-fast", fastCipher}, {"Rijndael", rijndaelCipher}} {
+		iv := make([]byte, c.cipher.BlockSize())
+
+		b.Run(c.name+"/ECB-goroutine-per-block", func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+			for i := 0; i < b.N; i++ {
+				ecbGoroutinePerBlock(c.cipher, data)
+			}
+		})
+
+		cases := []struct {
+			name string
+			run  func() ([]byte, error)
+		}{
+			{"ECB-encrypt", func() ([]byte, error) { return (&ECBMode{}).Encrypt(c.cipher, data) }},
+			{"CTR", func() ([]byte, error) { return NewCTRMode(iv).Encrypt(c.cipher, data) }},
+			{"CBC-decrypt", func() ([]byte, error) { return NewCBCMode(iv).Decrypt(c.cipher, data) }},
+			{"CFB-decrypt", func() ([]byte, error) { return NewCFBMode(iv).Decrypt(c.cipher, data) }},
+		}
+		for _, tc := range cases {
+			for _, workers := range []int{1, 0} {
+				label := "serial"
+				if workers == 0 {
+					label = fmt.Sprintf("pool-%d", runtime.GOMAXPROCS(0))
+				}
+				b.Run(c.name+"/"+tc.name+"/"+label, func(b *testing.B) {
+					useWorkerPool(b, workers)
+					b.SetBytes(int64(len(data)))
+					for i := 0; i < b.N; i++ {
+						if _, err := tc.run(); err != nil {
+							b.Fatal(err)
+						}
+					}
+				})
+			}
+		}
+	}
+}
+
+// feedStream прогоняет data через Stream кусками длиной из pieces (по кругу)
+func feedStream(t *testing.T, s *Stream, data []byte, pieces []int) []byte {
+	t.Helper()
+	var out []byte
+	for i := 0; len(data) > 0; i++ {
+		n := pieces[i%len(pieces)]
+		if n > len(data) {
+			n = len(data)
+		}
+		var err error
+		if out, err = s.Update(out, data[:n]); err != nil {
+			t.Fatalf("Update failed: %v", err)
+		}
+		data = data[n:]
+	}
+	tail, err := s.Final()
+	if err != nil {
+		t.Fatalf("Final failed: %v", err)
+	}
+	return append(out, tail...)
+}
+
+func TestStreamMatchesOneShot(t *testing.T) {
+	for name, cipher := range newTestCiphers(t) {
+		blockSize := cipher.BlockSize()
+		iv := make([]byte, blockSize)
+		rand.Read(iv)
+
+		tested := map[string]Mode{
+			"ECB":         &ECBMode{},
+			"CBC":         NewCBCMode(iv),
+			"PCBC":        NewPCBCMode(iv),
+			"RandomDelta": NewRandomDeltaMode(iv),
+			"CFB":         NewCFBMode(iv),
+			"CFB8":        NewCFBSegmentMode(iv, 8),
+			"CFB5":        NewCFBSegmentMode(iv, 5),
+			"OFB":         NewOFBMode(iv),
+			"OFB12":       NewOFBSegmentMode(iv, 12),
+			"CTR":         NewCTRMode(iv),
+			"CBC-CS3":     NewCBCCSMode(iv, CS3),
+		}
+		if blockSize == 16 {
+			tested["GCM"] = NewGCMMode(iv[:12], []byte("header"))
+		}
+
+		for modeName, mode := range tested {
+			paddingMode := padding.None
+			switch mode.(type) {
+			case *ECBMode, *CBCMode, *PCBCMode, *RandomDeltaMode:
+				paddingMode = padding.PKCS7
+			}
+
+			for _, size := range []int{0, 1, blockSize - 1, blockSize, 5*blockSize + 3, 11*blockSize + 5} {
+				t.Run(fmt.Sprintf("%s-%s-%d", name, modeName, size), func(t *testing.T) {
+					plaintext := make([]byte, size)
+					rand.Read(plaintext)
+					padded, err := padding.Pad(plaintext, blockSize, paddingMode)
+					if err != nil {
+						t.Fatalf("Pad failed: %v", err)
+					}
+					expected, err := mode.Encrypt(cipher, padded)
+					if err != nil {
+						t.Skipf("mode does not accept %d bytes: %v", size, err)
+					}
+
+					for _, pieces := range [][]int{{1}, {3, blockSize + 1, 2}, {2*blockSize - 1, 7}, {len(plaintext) + 1}} {
+						enc, err := NewEncrypter(cipher, mode, paddingMode)
+						if err != nil {
+							t.Fatalf("NewEncrypter failed: %v", err)
+						}
+						ciphertext := feedStream(t, enc, plaintext, pieces)
+						if !bytes.Equal(ciphertext, expected) {
+							t.Fatalf("pieces %v: ciphertext differs from one-shot", pieces)
+						}
+
+						dec, err := NewDecrypter(cipher, mode, paddingMode)
+						if err != nil {
+							t.Fatalf("NewDecrypter failed: %v", err)
+						}
+						if decrypted := feedStream(t, dec, ciphertext, pieces); !bytes.Equal(decrypted, plaintext) {
+							t.Fatalf("pieces %v: round-trip failed", pieces)
+						}
+					}
+
+					// Process - одним фрагментом, как в одноразовом Encrypt контекста
+					enc, _ := NewEncrypter(cipher, mode, paddingMode)
+					ciphertext, err := enc.Process(nil, plaintext)
+					if err != nil || !bytes.Equal(ciphertext, expected) {
+						t.Fatalf("Process: ciphertext differs from one-shot (err %v)", err)
+					}
+					dec, _ := NewDecrypter(cipher, mode, paddingMode)
+					if decrypted, err := dec.Process(nil, ciphertext); err != nil || !bytes.Equal(decrypted, plaintext) {
+						t.Fatalf("Process: round-trip failed (err %v)", err)
+					}
+				})
+			}
+		}
+	}
+}
+
+func TestStreamStateAndErrors(t *testing.T) {
+	cipher := newTestCiphers(t)["DES"]
+	iv := make([]byte, 8)
+
+	if _, err := NewEncrypter(cipher, NewCTRMode(iv), padding.PKCS7); err == nil {
+		t.Error("Expected error for padding with CTR")
+	}
+
+	enc, err := NewEncrypter(cipher, NewCBCMode(iv), padding.PKCS7)
+	if err != nil {
+		t.Fatalf("NewEncrypter failed: %v", err)
+	}
+	if out, _ := enc.Update(nil, make([]byte, 12)); len(out) != 8 {
+		t.Errorf("Update returned %d bytes, want one full block", len(out))
+	}
+	if _, err := enc.Final(); err != nil {
+		t.Fatalf("Final failed: %v", err)
+	}
+	if _, err := enc.Update(nil, []byte{1}); !errors.Is(err, ErrStreamFinished) {
+		t.Errorf("Update after Final: got %v, want ErrStreamFinished", err)
+	}
+	if _, err := enc.Final(); !errors.Is(err, ErrStreamFinished) {
+		t.Errorf("Final after Final: got %v, want ErrStreamFinished", err)
+	}
+
+	// выровненные данные идут в режим напрямую, буферизуется только хвост
+	direct, _ := NewEncrypter(cipher, NewCBCMode(iv), padding.PKCS7)
+	direct.Update(nil, make([]byte, 64))
+	if cap(direct.pending) != 0 {
+		t.Errorf("aligned Update buffered %d bytes", cap(direct.pending))
+	}
+	direct.Update(nil, make([]byte, 67))
+	if len(direct.pending) != 3 {
+		t.Errorf("pending holds %d bytes, want the 3-byte tail", len(direct.pending))
+	}
+
+	// без набивки блочный режим не может закончить поток на неполном блоке
+	raw, _ := NewEncrypter(cipher, &ECBMode{}, padding.None)
+	raw.Update(nil, make([]byte, 5))
+	if _, err := raw.Final(); err == nil {
+		t.Error("Expected error for unaligned tail without padding")
+	}
+}
+
+// batchOnlyCipher запрещает поблочные вызовы, чтобы убедиться, что режим идет через BatchCipher
+type batchOnlyCipher struct {
+	*des.FastDESCipher
+}
+
+func (c batchOnlyCipher) EncryptBlock([]byte) ([]byte, error) {
+	return nil, errors.New("EncryptBlock called instead of EncryptBlocks")
+}
+
+func (c batchOnlyCipher) DecryptBlock([]byte) ([]byte, error) {
+	return nil, errors.New("DecryptBlock called instead of DecryptBlocks")
+}
+
+// TestCTRMemory гамма считается пакетами, так что кроме выхода CTR почти ничего не выделяет
+func TestCTRMemory(t *testing.T) {
+	useWorkerPool(t, 1)
+	cipher := des.NewFastDESCipher()
+	cipher.SetKey([]byte{0x13, 0x34, 0x57, 0x79, 0x9B, 0xBC, 0xDF, 0xF1})
+	mode := NewCTRMode(make([]byte, 8))
+	data := make([]byte, 1<<20)
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	if _, err := mode.Encrypt(cipher, data); err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+	runtime.ReadMemStats(&after)
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > uint64(len(data))*5/4 {
+		t.Errorf("CTR allocated %d bytes for a %d-byte message", allocated, len(data))
+	}
+}
+
+func TestBatchCipherInECBAndCTR(t *testing.T) {
+	key := []byte{0x13, 0x34, 0x57, 0x79, 0x9B, 0xBC, 0xDF, 0xF1}
+	reference := des.NewDESCipher()
+	reference.SetKey(key)
+	bitsliced := des.NewFastDESCipher().(*des.FastDESCipher)
+	bitsliced.SetKey(key)
+	batch := batchOnlyCipher{bitsliced}
+
+	iv := make([]byte, 8)
+	rand.Read(iv)
+	plaintext := make([]byte, 300*8)
+	rand.Read(plaintext)
+
+	for _, workers := range []int{1, 4} {
+		useWorkerPool(t, workers)
+		for name, mode := range map[string]Mode{"ECB": &ECBMode{}, "CTR": NewCTRMode(iv)} {
+			expected, err := mode.Encrypt(reference, plaintext)
+			if err != nil {
+				t.Fatalf("%s: reference Encrypt failed: %v", name, err)
+			}
+			ciphertext, err := mode.Encrypt(batch, plaintext)
+			if err != nil {
+				t.Fatalf("%s: batch Encrypt failed: %v", name, err)
+			}
+			if !bytes.Equal(ciphertext, expected) {
+				t.Errorf("%s, %d workers: bitsliced DES differs from reference", name, workers)
+			}
+			decrypted, err := mode.Decrypt(batch, ciphertext[:len(ciphertext)-8])
+			if err != nil {
+				t.Fatalf("%s: batch Decrypt failed: %v", name, err)
+			}
+			if !bytes.Equal(decrypted, plaintext[:len(plaintext)-8]) {
+				t.Errorf("%s, %d workers: round-trip failed", name, workers)
+			}
+		}
+	}
+}
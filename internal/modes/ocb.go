@@ -0,0 +1,269 @@
+package modes
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"math/bits"
+
+	"github.com/Qwental/crypota/internal/gfield"
+	"github.com/Qwental/crypota/internal/interfaces"
+)
+
+const (
+	OCBBlockSize = 16
+	OCBMaxNonce  = 15
+)
+
+// OCBMode OCB3 (RFC 7253): однопроходный режим, каждый блок шифруется как
+// E(P xor Offset) xor Offset, а тег - шифр от контрольной суммы открытого текста.
+// Определен только для 128-битных блоков
+type OCBMode struct {
+	nonce          []byte
+	additionalData []byte
+	tagSize        int
+}
+
+func NewOCBMode(nonce []byte, tagSize int, additionalData []byte) (*OCBMode, error) {
+	if tagSize <= 0 || tagSize > OCBBlockSize {
+		return nil, fmt.Errorf("OCB tag size must be in [1, %d], got %d", OCBBlockSize, tagSize)
+	}
+	return &OCBMode{nonce: nonce, additionalData: additionalData, tagSize: tagSize}, nil
+}
+
+func (m *OCBMode) Encrypt(cipher interfaces.BlockCipher, plaintext []byte) ([]byte, error) {
+	return m.Seal(cipher, m.nonce, plaintext, m.additionalData)
+}
+
+func (m *OCBMode) Decrypt(cipher interfaces.BlockCipher, ciphertext []byte) ([]byte, error) {
+	return m.Open(cipher, m.nonce, ciphertext, m.additionalData)
+}
+
+func (m *OCBMode) Overhead() int {
+	return m.tagSize
+}
+
+func (m *OCBMode) Seal(cipher interfaces.BlockCipher, nonce, plaintext, additionalData []byte) ([]byte, error) {
+	output := make([]byte, len(plaintext)+m.tagSize)
+	tag, err := m.process(cipher, nonce, output[:len(plaintext)], plaintext, additionalData, true)
+	if err != nil {
+		return nil, err
+	}
+	copy(output[len(plaintext):], tag)
+	return output, nil
+}
+
+func (m *OCBMode) Open(cipher interfaces.BlockCipher, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(ciphertext) < m.tagSize {
+		return nil, fmt.Errorf("ciphertext too short for OCB tag")
+	}
+	data := ciphertext[:len(ciphertext)-m.tagSize]
+	plaintext := make([]byte, len(data))
+	tag, err := m.process(cipher, nonce, plaintext, data, additionalData, false)
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare(tag, ciphertext[len(data):]) != 1 {
+		for i := range plaintext {
+			plaintext[i] = 0
+		}
+		return nil, ErrAuthenticationFailed
+	}
+	return plaintext, nil
+}
+
+// ocbKeys - L_*, L_$ и ленивая таблица L_i = double(L_{i-1})
+type ocbKeys struct {
+	lStar   []byte
+	lDollar []byte
+	l       [][]byte
+}
+
+func newOCBKeys(cipher interfaces.BlockCipher) (*ocbKeys, error) {
+	lStar, err := cipher.EncryptBlock(make([]byte, OCBBlockSize))
+	if err != nil {
+		return nil, fmt.Errorf("L_* generation failed: %w", err)
+	}
+	lDollar, err := gfield.Double(lStar)
+	if err != nil {
+		return nil, err
+	}
+	l0, err := gfield.Double(lDollar)
+	if err != nil {
+		return nil, err
+	}
+	return &ocbKeys{lStar: lStar, lDollar: lDollar, l: [][]byte{l0}}, nil
+}
+
+// forBlock возвращает L_{ntz(i)} для i-го блока (i >= 1)
+func (k *ocbKeys) forBlock(i int) ([]byte, error) {
+	idx := bits.TrailingZeros(uint(i))
+	for len(k.l) <= idx {
+		next, err := gfield.Double(k.l[len(k.l)-1])
+		if err != nil {
+			return nil, err
+		}
+		k.l = append(k.l, next)
+	}
+	return k.l[idx], nil
+}
+
+func xorInto(dst, src []byte) {
+	for i := range src {
+		dst[i] ^= src[i]
+	}
+}
+
+// process выполняет проход OCB в нужную сторону и возвращает тег
+func (m *OCBMode) process(cipher interfaces.BlockCipher, nonce, dst, src, additionalData []byte, encrypt bool) ([]byte, error) {
+	if cipher.BlockSize() != OCBBlockSize {
+		return nil, fmt.Errorf("OCB requires %d-byte block cipher, got %d", OCBBlockSize, cipher.BlockSize())
+	}
+	if len(nonce) == 0 || len(nonce) > OCBMaxNonce {
+		return nil, fmt.Errorf("OCB nonce must be 1..%d bytes, got %d", OCBMaxNonce, len(nonce))
+	}
+
+	keys, err := newOCBKeys(cipher)
+	if err != nil {
+		return nil, err
+	}
+	offset, err := m.initialOffset(cipher, nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	checksum := make([]byte, OCBBlockSize)
+	fullBlocks := len(src) / OCBBlockSize
+	for i := 1; i <= fullBlocks; i++ {
+		l, err := keys.forBlock(i)
+		if err != nil {
+			return nil, err
+		}
+		xorInto(offset, l)
+
+		start := (i - 1) * OCBBlockSize
+		block := make([]byte, OCBBlockSize)
+		copy(block, src[start:start+OCBBlockSize])
+		xorInto(block, offset)
+
+		var processed []byte
+		if encrypt {
+			xorInto(checksum, src[start:start+OCBBlockSize])
+			processed, err = cipher.EncryptBlock(block)
+		} else {
+			processed, err = cipher.DecryptBlock(block)
+		}
+		if err != nil {
+			return nil, err
+		}
+		xorInto(processed, offset)
+		copy(dst[start:], processed)
+		if !encrypt {
+			xorInto(checksum, processed)
+		}
+	}
+
+	if tail := len(src) % OCBBlockSize; tail != 0 {
+		start := fullBlocks * OCBBlockSize
+		xorInto(offset, keys.lStar)
+		pad, err := cipher.EncryptBlock(offset)
+		if err != nil {
+			return nil, err
+		}
+		for j := 0; j < tail; j++ {
+			dst[start+j] = src[start+j] ^ pad[j]
+		}
+
+		plainTail := src[start:]
+		if !encrypt {
+			plainTail = dst[start:]
+		}
+		padded := make([]byte, OCBBlockSize)
+		copy(padded, plainTail)
+		padded[tail] = 0x80
+		xorInto(checksum, padded)
+	}
+
+	xorInto(checksum, offset)
+	xorInto(checksum, keys.lDollar)
+	tag, err := cipher.EncryptBlock(checksum)
+	if err != nil {
+		return nil, err
+	}
+
+	hash, err := ocbHash(cipher, keys, additionalData)
+	if err != nil {
+		return nil, err
+	}
+	xorInto(tag, hash)
+	return tag[:m.tagSize], nil
+}
+
+// initialOffset считает Offset_0 из nonce через Ktop и Stretch
+func (m *OCBMode) initialOffset(cipher interfaces.BlockCipher, nonce []byte) ([]byte, error) {
+	formatted := make([]byte, OCBBlockSize)
+	formatted[0] = byte((m.tagSize * 8 % 128) << 1)
+	formatted[OCBBlockSize-len(nonce)-1] |= 0x01
+	copy(formatted[OCBBlockSize-len(nonce):], nonce)
+
+	bottom := int(formatted[OCBBlockSize-1] & 0x3F)
+	formatted[OCBBlockSize-1] &= 0xC0
+
+	ktop, err := cipher.EncryptBlock(formatted)
+	if err != nil {
+		return nil, err
+	}
+
+	stretch := make([]byte, 24)
+	copy(stretch, ktop)
+	for i := 0; i < 8; i++ {
+		stretch[16+i] = ktop[i] ^ ktop[i+1]
+	}
+
+	offset := make([]byte, OCBBlockSize)
+	byteShift, bitShift := bottom/8, uint(bottom%8)
+	for i := range offset {
+		offset[i] = stretch[i+byteShift] << bitShift
+		if bitShift != 0 {
+			offset[i] |= stretch[i+byteShift+1] >> (8 - bitShift)
+		}
+	}
+	return offset, nil
+}
+
+// ocbHash - функция HASH(K, A) из RFC 7253 для ассоциированных данных
+func ocbHash(cipher interfaces.BlockCipher, keys *ocbKeys, additionalData []byte) ([]byte, error) {
+	sum := make([]byte, OCBBlockSize)
+	offset := make([]byte, OCBBlockSize)
+
+	fullBlocks := len(additionalData) / OCBBlockSize
+	for i := 1; i <= fullBlocks; i++ {
+		l, err := keys.forBlock(i)
+		if err != nil {
+			return nil, err
+		}
+		xorInto(offset, l)
+
+		block := make([]byte, OCBBlockSize)
+		copy(block, additionalData[(i-1)*OCBBlockSize:i*OCBBlockSize])
+		xorInto(block, offset)
+		encrypted, err := cipher.EncryptBlock(block)
+		if err != nil {
+			return nil, err
+		}
+		xorInto(sum, encrypted)
+	}
+
+	if tail := len(additionalData) % OCBBlockSize; tail != 0 {
+		xorInto(offset, keys.lStar)
+		block := make([]byte, OCBBlockSize)
+		copy(block, additionalData[fullBlocks*OCBBlockSize:])
+		block[tail] = 0x80
+		xorInto(block, offset)
+		encrypted, err := cipher.EncryptBlock(block)
+		if err != nil {
+			return nil, err
+		}
+		xorInto(sum, encrypted)
+	}
+	return sum, nil
+}
@@ -0,0 +1,95 @@
+package modes
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// minChunkBlocks - меньше этого блоков в одном куске делить работу невыгодно
+const minChunkBlocks = 64
+
+// WorkerPool ограничивает число горутин, одновременно шифрующих блоки во всех режимах.
+// Работа делится на куски подряд идущих блоков, каждый кусок занимает один слот пула
+type WorkerPool struct {
+	slots chan struct{}
+}
+
+// NewWorkerPool создает пул на workers слотов; workers <= 0 означает GOMAXPROCS
+func NewWorkerPool(workers int) *WorkerPool {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	return &WorkerPool{slots: make(chan struct{}, workers)}
+}
+
+func (p *WorkerPool) Workers() int {
+	return cap(p.slots)
+}
+
+var sharedPool atomic.Pointer[WorkerPool]
+
+func init() {
+	sharedPool.Store(NewWorkerPool(0))
+}
+
+// SetWorkerPool заменяет общий пул, которым пользуются ECB, CTR и расшифрование CBC/CFB;
+// nil возвращает пул по умолчанию на GOMAXPROCS слотов
+func SetWorkerPool(p *WorkerPool) {
+	if p == nil {
+		p = NewWorkerPool(0)
+	}
+	sharedPool.Store(p)
+}
+
+// DefaultWorkerPool возвращает текущий общий пул
+func DefaultWorkerPool() *WorkerPool {
+	return sharedPool.Load()
+}
+
+// run делит блоки [0, numBlocks) на куски и вызывает process(from, to) для каждого,
+// возвращая первую ошибку по порядку кусков
+func (p *WorkerPool) run(numBlocks int, process func(from, to int) error) error {
+	chunks := p.Workers()
+	if limit := numBlocks / minChunkBlocks; limit < chunks {
+		chunks = limit
+	}
+	if chunks <= 1 {
+		return process(0, numBlocks)
+	}
+
+	perChunk := (numBlocks + chunks - 1) / chunks
+	errs := make([]error, chunks)
+	var wg sync.WaitGroup
+	for c := 0; c < chunks; c++ {
+		from := c * perChunk
+		to := from + perChunk
+		if to > numBlocks {
+			to = numBlocks
+		}
+		if from >= to {
+			break
+		}
+
+		wg.Add(1)
+		go func(c, from, to int) {
+			defer wg.Done()
+			p.slots <- struct{}{}
+			defer func() { <-p.slots }()
+			errs[c] = process(from, to)
+		}(c, from, to)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parallelBlocks прогоняет блоки через общий пул
+func parallelBlocks(numBlocks int, process func(from, to int) error) error {
+	return DefaultWorkerPool().run(numBlocks, process)
+}
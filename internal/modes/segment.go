@@ -0,0 +1,102 @@
+package modes
+
+import (
+	"fmt"
+
+	"github.com/Qwental/crypota/internal/interfaces"
+)
+
+// SegmentSize размер сегмента CFB/OFB в битах, передается в params контекста
+type SegmentSize int
+
+// segmentFeedback - какие биты вдвигаются в регистр сдвига после каждого сегмента
+type segmentFeedback int
+
+const (
+	feedbackOutput    segmentFeedback = iota // шифртекст на выходе (шифрование CFB)
+	feedbackInput                            // шифртекст на входе (расшифрование CFB)
+	feedbackKeystream                        // гамма (OFB)
+)
+
+// isPartialSegment проверяет размер сегмента и сообщает, меньше ли он блока;
+// 0 и полный блок обрабатываются обычным поблочным кодом
+func isPartialSegment(segmentBits, blockSize int) (bool, error) {
+	if segmentBits < 0 || segmentBits > blockSize*8 {
+		return false, fmt.Errorf("segment size must be in [1, %d] bits, got %d", blockSize*8, segmentBits)
+	}
+	return segmentBits != 0 && segmentBits != blockSize*8, nil
+}
+
+// segmentAlignment - минимальное число байт, в котором укладывается целое число сегментов
+func segmentAlignment(segmentBits int) int {
+	if segmentBits == 0 {
+		return 1
+	}
+	a, b := segmentBits, 8
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return segmentBits / a
+}
+
+// Alignment - кратность фрагментов при продолжении цепочки через Next
+func (m *CFBMode) Alignment() int {
+	return segmentAlignment(m.segmentBits)
+}
+
+func (m *OFBMode) Alignment() int {
+	return segmentAlignment(m.segmentBits)
+}
+
+func bitAt(data []byte, i int) byte {
+	return data[i/8] >> (7 - uint(i%8)) & 1
+}
+
+func setBit(data []byte, i int, bit byte) {
+	data[i/8] |= bit << (7 - uint(i%8))
+}
+
+// processSegments обрабатывает данные как битовую строку сегментами по s бит:
+// O = E(I), выход = вход xor MSB_s(O), I = LSB_{b-s}(I) || обратная связь.
+// Последний сегмент может быть короче s, после него регистр уже не нужен
+func processSegments(cipher interfaces.BlockCipher, iv, data []byte, s int, feedback segmentFeedback) ([]byte, error) {
+	blockBits := cipher.BlockSize() * 8
+	register := make([]byte, len(iv))
+	copy(register, iv)
+	output := make([]byte, len(data))
+
+	totalBits := len(data) * 8
+	for offset := 0; offset < totalBits; offset += s {
+		keystream, err := cipher.EncryptBlock(register)
+		if err != nil {
+			return nil, err
+		}
+		n := s
+		if offset+n > totalBits {
+			n = totalBits - offset
+		}
+		for k := 0; k < n; k++ {
+			setBit(output, offset+k, bitAt(data, offset+k)^bitAt(keystream, k))
+		}
+		if n < s {
+			break
+		}
+
+		source, sourceOffset := output, offset
+		switch feedback {
+		case feedbackInput:
+			source = data
+		case feedbackKeystream:
+			source, sourceOffset = keystream, 0
+		}
+		shifted := make([]byte, len(register))
+		for i := 0; i < blockBits-s; i++ {
+			setBit(shifted, i, bitAt(register, i+s))
+		}
+		for i := 0; i < s; i++ {
+			setBit(shifted, blockBits-s+i, bitAt(source, sourceOffset+i))
+		}
+		register = shifted
+	}
+	return output, nil
+}
@@ -0,0 +1,151 @@
+package modes
+
+import (
+	"crypto/subtle"
+	"fmt"
+
+	"github.com/Qwental/crypota/internal/cmac"
+	"github.com/Qwental/crypota/internal/gfield"
+	"github.com/Qwental/crypota/internal/interfaces"
+)
+
+const SIVBlockSize = 16
+
+// SIVMode детерминированный режим SIV (RFC 5297): синтетический IV = S2V по вектору
+// ассоциированных данных и открытому тексту, он же служит тегом и начальным счетчиком CTR.
+// macCipher должен быть заключен на K1 (S2V), шифр, передаваемый в Encrypt/Decrypt, - на K2 (CTR)
+type SIVMode struct {
+	macCipher      interfaces.BlockCipher
+	associatedData [][]byte
+}
+
+func NewSIVMode(macCipher interfaces.BlockCipher, associatedData ...[]byte) *SIVMode {
+	return &SIVMode{macCipher: macCipher, associatedData: associatedData}
+}
+
+func (m *SIVMode) Encrypt(cipher interfaces.BlockCipher, plaintext []byte) ([]byte, error) {
+	return m.SealVector(cipher, plaintext, m.associatedData...)
+}
+
+func (m *SIVMode) Decrypt(cipher interfaces.BlockCipher, ciphertext []byte) ([]byte, error) {
+	return m.OpenVector(cipher, ciphertext, m.associatedData...)
+}
+
+func (m *SIVMode) Overhead() int {
+	return SIVBlockSize
+}
+
+// Seal - AEAD-интерфейс: вектор состоит из additionalData и, если задан, nonce
+func (m *SIVMode) Seal(cipher interfaces.BlockCipher, nonce, plaintext, additionalData []byte) ([]byte, error) {
+	return m.SealVector(cipher, plaintext, aeadVector(nonce, additionalData)...)
+}
+
+func (m *SIVMode) Open(cipher interfaces.BlockCipher, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	return m.OpenVector(cipher, ciphertext, aeadVector(nonce, additionalData)...)
+}
+
+func aeadVector(nonce, additionalData []byte) [][]byte {
+	vector := [][]byte{additionalData}
+	if len(nonce) > 0 {
+		vector = append(vector, nonce)
+	}
+	return vector
+}
+
+// SealVector возвращает V || C, где V = S2V(AD1, ..., ADn, P)
+func (m *SIVMode) SealVector(cipher interfaces.BlockCipher, plaintext []byte, associatedData ...[]byte) ([]byte, error) {
+	if err := m.check(cipher); err != nil {
+		return nil, err
+	}
+	v, err := s2v(m.macCipher, append(associatedData[:len(associatedData):len(associatedData)], plaintext))
+	if err != nil {
+		return nil, err
+	}
+	ciphertext, err := NewCTRMode(sivCounter(v)).Encrypt(cipher, plaintext)
+	if err != nil {
+		return nil, err
+	}
+	return append(v, ciphertext...), nil
+}
+
+// OpenVector расшифровывает V || C и отдает открытый текст, только если S2V совпал с V
+func (m *SIVMode) OpenVector(cipher interfaces.BlockCipher, ciphertext []byte, associatedData ...[]byte) ([]byte, error) {
+	if err := m.check(cipher); err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < SIVBlockSize {
+		return nil, fmt.Errorf("ciphertext too short for SIV")
+	}
+	v := ciphertext[:SIVBlockSize]
+	plaintext, err := NewCTRMode(sivCounter(v)).Decrypt(cipher, ciphertext[SIVBlockSize:])
+	if err != nil {
+		return nil, err
+	}
+
+	expected, err := s2v(m.macCipher, append(associatedData[:len(associatedData):len(associatedData)], plaintext))
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare(expected, v) != 1 {
+		for i := range plaintext {
+			plaintext[i] = 0
+		}
+		return nil, ErrAuthenticationFailed
+	}
+	return plaintext, nil
+}
+
+func (m *SIVMode) check(cipher interfaces.BlockCipher) error {
+	if m.macCipher == nil {
+		return fmt.Errorf("SIV requires a MAC cipher")
+	}
+	if cipher.BlockSize() != SIVBlockSize || m.macCipher.BlockSize() != SIVBlockSize {
+		return fmt.Errorf("SIV requires %d-byte block ciphers", SIVBlockSize)
+	}
+	return nil
+}
+
+// sivCounter обнуляет 31-й и 63-й биты справа, чтобы счетчик можно было увеличивать в 64-битной арифметике
+func sivCounter(v []byte) []byte {
+	counter := make([]byte, SIVBlockSize)
+	copy(counter, v)
+	counter[8] &= 0x7F
+	counter[12] &= 0x7F
+	return counter
+}
+
+// s2v - псевдослучайная функция над вектором строк из RFC 5297 (последний элемент - открытый текст)
+func s2v(cipher interfaces.BlockCipher, strings [][]byte) ([]byte, error) {
+	d, err := cmac.MAC(cipher, make([]byte, SIVBlockSize))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, s := range strings[:len(strings)-1] {
+		if d, err = gfield.Double(d); err != nil {
+			return nil, err
+		}
+		mac, err := cmac.MAC(cipher, s)
+		if err != nil {
+			return nil, err
+		}
+		xorInto(d, mac)
+	}
+
+	last := strings[len(strings)-1]
+	var t []byte
+	if len(last) >= SIVBlockSize {
+		t = make([]byte, len(last))
+		copy(t, last)
+		xorInto(t[len(t)-SIVBlockSize:], d)
+	} else {
+		if t, err = gfield.Double(d); err != nil {
+			return nil, err
+		}
+		padded := make([]byte, SIVBlockSize)
+		copy(padded, last)
+		padded[len(last)] = 0x80
+		xorInto(t, padded)
+	}
+	return cmac.MAC(cipher, t)
+}
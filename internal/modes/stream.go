@@ -0,0 +1,287 @@
+package modes
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Qwental/crypota/internal/interfaces"
+	"github.com/Qwental/crypota/internal/padding"
+)
+
+// ErrStreamFinished - Update или Final после Final
+var ErrStreamFinished = errors.New("stream already finished")
+
+type streamKind int
+
+const (
+	// keystreamKind - CTR, OFB и CFB с полным блоком: выход отдается сразу, побайтно
+	keystreamKind streamKind = iota
+	// blockKind - остальные цепочечные режимы: данные копятся до целого числа блоков (сегментов)
+	blockKind
+	// wholeKind - AEAD и кража шифртекста: нужно все сообщение, выход появляется в Final
+	wholeKind
+)
+
+// Stream инкрементальное шифрование (расшифрование) поверх любого режима:
+// Update принимает данные кусками произвольной длины, Final дообрабатывает хвост
+// и снимает (добавляет) набивку. Состояние цепочки переносится через ChainableMode.Next
+type Stream struct {
+	cipher      interfaces.BlockCipher
+	mode        Mode
+	encrypt     bool
+	paddingMode padding.PaddingMode
+	kind        streamKind
+	unit        int
+	pending     []byte
+
+	// для keystreamKind: гамма текущего блока, сколько ее байт израсходовано
+	// и (для CFB) накопленный блок шифртекста для обратной связи
+	keystream []byte
+	used      int
+	feedback  []byte
+	cfb       bool
+
+	finished bool
+}
+
+// NewEncrypter создает инкрементальный шифратор; набивка применяется только в блочных режимах
+// (ECB, CBC, PCBC, RandomDelta), для остальных paddingMode должен быть padding.None
+func NewEncrypter(cipher interfaces.BlockCipher, mode Mode, paddingMode padding.PaddingMode) (*Stream, error) {
+	return newStream(cipher, mode, paddingMode, true)
+}
+
+func NewDecrypter(cipher interfaces.BlockCipher, mode Mode, paddingMode padding.PaddingMode) (*Stream, error) {
+	return newStream(cipher, mode, paddingMode, false)
+}
+
+func newStream(cipher interfaces.BlockCipher, mode Mode, paddingMode padding.PaddingMode, encrypt bool) (*Stream, error) {
+	blockSize := cipher.BlockSize()
+	s := &Stream{
+		cipher:      cipher,
+		mode:        mode,
+		encrypt:     encrypt,
+		paddingMode: paddingMode,
+		kind:        blockKind,
+		unit:        blockSize,
+	}
+
+	padded := true
+	switch m := mode.(type) {
+	case *CTRMode:
+		s.kind = keystreamKind
+	case *OFBMode:
+		if partial, _ := isPartialSegment(m.segmentBits, blockSize); !partial {
+			s.kind = keystreamKind
+		}
+		padded = false
+	case *CFBMode:
+		if partial, _ := isPartialSegment(m.segmentBits, blockSize); !partial {
+			s.kind = keystreamKind
+			s.cfb = true
+		}
+		padded = false
+	default:
+		if _, ok := mode.(ChainableMode); !ok {
+			s.kind = wholeKind
+			padded = false
+		}
+	}
+	if s.kind == keystreamKind {
+		padded = false
+	}
+	if !padded && paddingMode != padding.None {
+		return nil, fmt.Errorf("mode does not use padding, pass padding.None")
+	}
+
+	if aligned, ok := mode.(AlignedMode); ok {
+		for s.unit%aligned.Alignment() != 0 {
+			s.unit += blockSize
+		}
+	}
+	return s, nil
+}
+
+// Update обрабатывает src и дописывает готовый выход к dst
+func (s *Stream) Update(dst, src []byte) ([]byte, error) {
+	if s.finished {
+		return dst, ErrStreamFinished
+	}
+	switch s.kind {
+	case keystreamKind:
+		return s.updateKeystream(dst, src)
+	case blockKind:
+		return s.updateBlocks(dst, src)
+	default:
+		s.pending = append(s.pending, src...)
+		return dst, nil
+	}
+}
+
+// Final обрабатывает оставшиеся данные; после него поток использовать нельзя
+func (s *Stream) Final() ([]byte, error) {
+	if s.finished {
+		return nil, ErrStreamFinished
+	}
+	s.finished = true
+
+	switch s.kind {
+	case keystreamKind:
+		return nil, nil
+	case wholeKind:
+		return s.process(s.mode, s.pending)
+	}
+
+	if !s.encrypt {
+		out, err := s.process(s.mode, s.pending)
+		if err != nil || s.paddingMode == padding.None {
+			return out, err
+		}
+		plaintext, err := padding.Unpad(out, s.paddingMode)
+		if err != nil {
+			return nil, fmt.Errorf("unpadding failed: %w", err)
+		}
+		return plaintext, nil
+	}
+
+	data := s.pending
+	if s.paddingMode != padding.None {
+		var err error
+		if data, err = padding.Pad(s.pending, s.cipher.BlockSize(), s.paddingMode); err != nil {
+			return nil, fmt.Errorf("padding failed: %w", err)
+		}
+	}
+	return s.process(s.mode, data)
+}
+
+// Process обрабатывает последний фрагмент и завершает поток - то же, что Update и Final,
+// но режимам, которым нужно все сообщение, src отдается без копии во внутренний буфер
+func (s *Stream) Process(dst, src []byte) ([]byte, error) {
+	if s.kind == wholeKind && !s.finished && len(s.pending) == 0 {
+		s.pending = src
+		out, err := s.Final()
+		s.pending = nil
+		if err != nil {
+			return dst, err
+		}
+		return append(dst, out...), nil
+	}
+
+	dst, err := s.Update(dst, src)
+	if err != nil {
+		return dst, err
+	}
+	tail, err := s.Final()
+	if err != nil {
+		return dst, err
+	}
+	return append(dst, tail...), nil
+}
+
+func (s *Stream) process(mode Mode, data []byte) ([]byte, error) {
+	if s.encrypt {
+		return mode.Encrypt(s.cipher, data)
+	}
+	return mode.Decrypt(s.cipher, data)
+}
+
+// advance обрабатывает целые блоки (сегменты) и переводит режим в состояние после них
+func (s *Stream) advance(dst, data []byte) ([]byte, error) {
+	if len(data) == 0 {
+		return dst, nil
+	}
+	out, err := s.process(s.mode, data)
+	if err != nil {
+		return dst, err
+	}
+	chainable := s.mode.(ChainableMode)
+	if s.encrypt {
+		s.mode = chainable.Next(data, out)
+	} else {
+		s.mode = chainable.Next(out, data)
+	}
+	return append(dst, out...), nil
+}
+
+// holdsLast - при расшифровании с набивкой последний целый блок ждет Final, чтобы снять набивку
+func (s *Stream) holdsLast() bool {
+	return !s.encrypt && s.paddingMode != padding.None
+}
+
+// updateBlocks копит в pending только неполный хвост (или удержанный последний блок),
+// выровненная часть src обрабатывается без копирования
+func (s *Stream) updateBlocks(dst, src []byte) ([]byte, error) {
+	var err error
+	if len(s.pending) > 0 {
+		need := min(s.unit-len(s.pending), len(src))
+		s.pending = append(s.pending, src[:need]...)
+		src = src[need:]
+		if len(s.pending) < s.unit || (len(src) == 0 && s.holdsLast()) {
+			return dst, nil
+		}
+		if dst, err = s.advance(dst, s.pending); err != nil {
+			return dst, err
+		}
+		s.pending = s.pending[:0]
+	}
+
+	n := len(src) - len(src)%s.unit
+	if n == len(src) && n > 0 && s.holdsLast() {
+		n -= s.unit
+	}
+	if dst, err = s.advance(dst, src[:n]); err != nil {
+		return dst, err
+	}
+	s.pending = append(s.pending, src[n:]...)
+	return dst, nil
+}
+
+func (s *Stream) updateKeystream(dst, src []byte) ([]byte, error) {
+	blockSize := s.cipher.BlockSize()
+	for len(src) > 0 {
+		// с границы блока целые блоки отдаем режиму одним вызовом
+		if s.used == 0 && len(src) >= blockSize {
+			n := len(src) - len(src)%blockSize
+			var err error
+			if dst, err = s.advance(dst, src[:n]); err != nil {
+				return dst, err
+			}
+			src = src[n:]
+			continue
+		}
+
+		if s.used == 0 {
+			zeros := make([]byte, blockSize)
+			keystream, err := s.mode.Encrypt(s.cipher, zeros)
+			if err != nil {
+				return dst, err
+			}
+			s.keystream = keystream
+			s.feedback = make([]byte, 0, blockSize)
+			if !s.cfb {
+				s.mode = s.mode.(ChainableMode).Next(zeros, keystream)
+			}
+		}
+
+		for len(src) > 0 && s.used < blockSize {
+			out := src[0] ^ s.keystream[s.used]
+			if s.cfb {
+				if s.encrypt {
+					s.feedback = append(s.feedback, out)
+				} else {
+					s.feedback = append(s.feedback, src[0])
+				}
+			}
+			dst = append(dst, out)
+			src = src[1:]
+			s.used++
+		}
+
+		if s.used == blockSize {
+			if s.cfb {
+				s.mode = s.mode.(ChainableMode).Next(nil, s.feedback)
+			}
+			s.used = 0
+		}
+	}
+	return dst, nil
+}
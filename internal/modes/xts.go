@@ -0,0 +1,123 @@
+package modes
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/Qwental/crypota/internal/gfield"
+	"github.com/Qwental/crypota/internal/interfaces"
+)
+
+const XTSBlockSize = 16
+
+// XTSMode XTS-режим для шифрования секторов (IEEE 1619). В отличие от Mode
+// работает с отдельными единицами данных: твик - номер сектора, зашифрованный
+// вторым ключом и умножаемый на alpha для каждого следующего блока.
+// Неполный последний блок обрабатывается кражей шифртекста
+type XTSMode struct {
+	dataCipher  interfaces.BlockCipher
+	tweakCipher interfaces.BlockCipher
+}
+
+// NewXTSMode принимает два шифра, заключенных на Key1 (данные) и Key2 (твик)
+func NewXTSMode(dataCipher, tweakCipher interfaces.BlockCipher) (*XTSMode, error) {
+	if dataCipher.BlockSize() != XTSBlockSize || tweakCipher.BlockSize() != XTSBlockSize {
+		return nil, fmt.Errorf("XTS requires %d-byte block ciphers", XTSBlockSize)
+	}
+	return &XTSMode{dataCipher: dataCipher, tweakCipher: tweakCipher}, nil
+}
+
+func (m *XTSMode) EncryptSector(sector uint64, plaintext []byte) ([]byte, error) {
+	return m.process(sector, plaintext, true)
+}
+
+func (m *XTSMode) DecryptSector(sector uint64, ciphertext []byte) ([]byte, error) {
+	return m.process(sector, ciphertext, false)
+}
+
+func (m *XTSMode) process(sector uint64, data []byte, encrypt bool) ([]byte, error) {
+	if len(data) < XTSBlockSize {
+		return nil, fmt.Errorf("XTS data unit must be at least %d bytes, got %d", XTSBlockSize, len(data))
+	}
+
+	sectorBlock := make([]byte, XTSBlockSize)
+	binary.LittleEndian.PutUint64(sectorBlock, sector)
+	tweak, err := m.tweakCipher.EncryptBlock(sectorBlock)
+	if err != nil {
+		return nil, fmt.Errorf("tweak encryption failed: %w", err)
+	}
+
+	output := make([]byte, len(data))
+	fullBlocks := len(data) / XTSBlockSize
+	tail := len(data) % XTSBlockSize
+
+	// при краже шифртекста последний полный блок обрабатывается отдельно
+	plainBlocks := fullBlocks
+	if tail != 0 {
+		plainBlocks--
+	}
+
+	for i := 0; i < plainBlocks; i++ {
+		offset := i * XTSBlockSize
+		block, err := m.xex(data[offset:offset+XTSBlockSize], tweak, encrypt)
+		if err != nil {
+			return nil, err
+		}
+		copy(output[offset:], block)
+		if tweak, err = gfield.MultiplyByAlpha(tweak); err != nil {
+			return nil, err
+		}
+	}
+
+	if tail == 0 {
+		return output, nil
+	}
+
+	nextTweak, err := gfield.MultiplyByAlpha(tweak)
+	if err != nil {
+		return nil, err
+	}
+	// при расшифровании предпоследний блок снимается следующим твиком, а не текущим
+	firstTweak, secondTweak := tweak, nextTweak
+	if !encrypt {
+		firstTweak, secondTweak = nextTweak, tweak
+	}
+
+	offset := plainBlocks * XTSBlockSize
+	stolen, err := m.xex(data[offset:offset+XTSBlockSize], firstTweak, encrypt)
+	if err != nil {
+		return nil, err
+	}
+
+	last := make([]byte, XTSBlockSize)
+	copy(last, data[offset+XTSBlockSize:])
+	copy(last[tail:], stolen[tail:])
+
+	block, err := m.xex(last, secondTweak, encrypt)
+	if err != nil {
+		return nil, err
+	}
+	copy(output[offset:], block)
+	copy(output[offset+XTSBlockSize:], stolen[:tail])
+	return output, nil
+}
+
+// xex - C = E(P xor T) xor T (или D при расшифровании)
+func (m *XTSMode) xex(block, tweak []byte, encrypt bool) ([]byte, error) {
+	input := make([]byte, XTSBlockSize)
+	copy(input, block)
+	xorInto(input, tweak)
+
+	var result []byte
+	var err error
+	if encrypt {
+		result, err = m.dataCipher.EncryptBlock(input)
+	} else {
+		result, err = m.dataCipher.DecryptBlock(input)
+	}
+	if err != nil {
+		return nil, err
+	}
+	xorInto(result, tweak)
+	return result, nil
+}
@@ -13,9 +13,13 @@ const (
 	ANSIX923
 	PKCS7
 	ISO10126
+	None // набивка отключена: длина данных должна подходить режиму сама
 )
 
 func Pad(data []byte, blockSize int, mode PaddingMode) ([]byte, error) {
+	if mode == None {
+		return data, nil
+	}
 	if blockSize <= 0 || blockSize > 255 {
 		return nil, fmt.Errorf("invalid block size: %d", blockSize)
 	}
@@ -58,6 +62,9 @@ func Pad(data []byte, blockSize int, mode PaddingMode) ([]byte, error) {
 }
 
 func Unpad(data []byte, mode PaddingMode) ([]byte, error) {
+	if mode == None {
+		return data, nil
+	}
 	if len(data) == 0 {
 		return nil, fmt.Errorf("cannot unpad empty data")
 	}
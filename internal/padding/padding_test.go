@@ -27,3 +27,23 @@ func TestPKCS7Padding(t *testing.T) {
 		t.Errorf("Unpadded data doesn't match original")
 	}
 }
+
+func TestNonePadding(t *testing.T) {
+	data := []byte("Hello")
+
+	padded, err := Pad(data, 8, None)
+	if err != nil {
+		t.Fatalf("Pad failed: %v", err)
+	}
+	if !bytes.Equal(padded, data) {
+		t.Errorf("None padding must leave data unchanged")
+	}
+
+	unpadded, err := Unpad(padded, None)
+	if err != nil {
+		t.Fatalf("Unpad failed: %v", err)
+	}
+	if !bytes.Equal(unpadded, data) {
+		t.Errorf("Unpadded data doesn't match original")
+	}
+}
@@ -0,0 +1,147 @@
+package paddingoracle
+
+import (
+	"fmt"
+
+	"github.com/Qwental/crypota/internal/context"
+	"github.com/Qwental/crypota/internal/padding"
+)
+
+// Oracle сообщает, снимается ли набивка PKCS7 после расшифрования CBC.
+// Вход - IV и блоки шифртекста подряд (как у контекста с PerMessageIV)
+type Oracle func(ciphertext []byte) bool
+
+// NewContextOracle строит оракул вокруг CipherContext.Decrypt: любая ошибка расшифрования
+// считается ошибкой набивки. Контекст должен быть CBC с PKCS7 и PerMessageIV, чтобы атакующий
+// мог подменять IV первого блока
+func NewContextOracle(ctx *context.CipherContext) Oracle {
+	return func(ciphertext []byte) bool {
+		_, err := ctx.Decrypt(ciphertext)
+		return err == nil
+	}
+}
+
+type AttackResult struct {
+	Plaintext []byte
+	Queries   int
+}
+
+type ForgeResult struct {
+	Ciphertext []byte
+	Queries    int
+}
+
+// PaddingOracleAttacker - атака Воденэ на CBC с PKCS7: каждый байт промежуточного
+// состояния D(C) подбирается не более чем за 256 запросов к оракулу
+type PaddingOracleAttacker struct {
+	oracle    Oracle
+	blockSize int
+	queries   int
+}
+
+func NewPaddingOracleAttacker(oracle Oracle, blockSize int) *PaddingOracleAttacker {
+	return &PaddingOracleAttacker{
+		oracle:    oracle,
+		blockSize: blockSize,
+	}
+}
+
+// Queries - сколько запросов к оракулу сделано за все время
+func (pa *PaddingOracleAttacker) Queries() int {
+	return pa.queries
+}
+
+func (pa *PaddingOracleAttacker) query(ciphertext []byte) bool {
+	pa.queries++
+	return pa.oracle(ciphertext)
+}
+
+// Decrypt восстанавливает открытый текст по IV || C1..Cn без ключа и снимает набивку
+func (pa *PaddingOracleAttacker) Decrypt(ciphertext []byte) (*AttackResult, error) {
+	bs := pa.blockSize
+	if len(ciphertext) < 2*bs || len(ciphertext)%bs != 0 {
+		return nil, fmt.Errorf("ciphertext must be IV plus whole blocks of %d bytes, got %d bytes", bs, len(ciphertext))
+	}
+
+	start := pa.queries
+	padded := make([]byte, 0, len(ciphertext)-bs)
+	for i := bs; i < len(ciphertext); i += bs {
+		intermediate, err := pa.intermediate(ciphertext[i : i+bs])
+		if err != nil {
+			return nil, fmt.Errorf("block %d: %w", i/bs, err)
+		}
+		for j := range intermediate {
+			padded = append(padded, intermediate[j]^ciphertext[i-bs+j])
+		}
+	}
+
+	plaintext, err := padding.Unpad(padded, padding.PKCS7)
+	if err != nil {
+		return nil, fmt.Errorf("recovered plaintext has invalid padding: %w", err)
+	}
+	return &AttackResult{Plaintext: plaintext, Queries: pa.queries - start}, nil
+}
+
+// Forge строит IV || C1..Cn, который расшифровывается в plaintext: последний блок
+// произвольный, а каждый предыдущий подбирается как D(C_i) xor P_i
+func (pa *PaddingOracleAttacker) Forge(plaintext []byte) (*ForgeResult, error) {
+	bs := pa.blockSize
+	padded, err := padding.Pad(plaintext, bs, padding.PKCS7)
+	if err != nil {
+		return nil, err
+	}
+
+	start := pa.queries
+	ciphertext := make([]byte, len(padded)+bs)
+	for i := len(padded); i > 0; i -= bs {
+		intermediate, err := pa.intermediate(ciphertext[i : i+bs])
+		if err != nil {
+			return nil, fmt.Errorf("block %d: %w", i/bs, err)
+		}
+		for j := range intermediate {
+			ciphertext[i-bs+j] = intermediate[j] ^ padded[i-bs+j]
+		}
+	}
+	return &ForgeResult{Ciphertext: ciphertext, Queries: pa.queries - start}, nil
+}
+
+// intermediate находит D(block) с конца: для набивки p байты после текущего уже
+// выставлены в I xor p, а текущий перебирается, пока оракул не примет набивку
+func (pa *PaddingOracleAttacker) intermediate(block []byte) ([]byte, error) {
+	bs := pa.blockSize
+	intermediate := make([]byte, bs)
+	probe := make([]byte, 2*bs)
+	copy(probe[bs:], block)
+
+	for p := 1; p <= bs; p++ {
+		pos := bs - p
+		for j := pos + 1; j < bs; j++ {
+			probe[j] = intermediate[j] ^ byte(p)
+		}
+
+		found := false
+		for guess := 0; guess < 256; guess++ {
+			probe[pos] = byte(guess)
+			if !pa.query(probe) {
+				continue
+			}
+			// для первого байта набивка могла оказаться 02 02 и длиннее: меняем соседний
+			// байт, и если оракул все еще доволен, набивка действительно 01
+			if p == 1 && pos > 0 {
+				probe[pos-1] ^= 0xFF
+				valid := pa.query(probe)
+				probe[pos-1] ^= 0xFF
+				if !valid {
+					continue
+				}
+			}
+			intermediate[pos] = byte(guess) ^ byte(p)
+			found = true
+			break
+		}
+		if !found {
+			return nil, fmt.Errorf("oracle accepted no padding for byte %d", pos)
+		}
+	}
+	return intermediate, nil
+}
@@ -0,0 +1,109 @@
+package paddingoracle
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/Qwental/crypota/internal/context"
+	"github.com/Qwental/crypota/internal/des"
+	"github.com/Qwental/crypota/internal/interfaces"
+	"github.com/Qwental/crypota/internal/modes"
+	"github.com/Qwental/crypota/internal/padding"
+	"github.com/Qwental/crypota/internal/rijndael"
+)
+
+func newVictim(t *testing.T, cipher interfaces.BlockCipher, key []byte) *context.CipherContext {
+	t.Helper()
+	ctx, err := context.NewCipherContext(cipher, key, modes.CBC, padding.PKCS7, nil, context.PerMessageIV)
+	if err != nil {
+		t.Fatalf("NewCipherContext failed: %v", err)
+	}
+	return ctx
+}
+
+func newTestVictims(t *testing.T) map[string]*context.CipherContext {
+	t.Helper()
+	rijndaelCipher, err := rijndael.NewRijndaelCipher(16, 16, 0x1B)
+	if err != nil {
+		t.Fatalf("NewRijndaelCipher failed: %v", err)
+	}
+	return map[string]*context.CipherContext{
+		"DES":      newVictim(t, des.NewDESCipher(), []byte{0x13, 0x34, 0x57, 0x79, 0x9B, 0xBC, 0xDF, 0xF1}),
+		"Rijndael": newVictim(t, rijndaelCipher, []byte("fedcba9876543210")),
+	}
+}
+
+func TestPaddingOracleRecoversPlaintext(t *testing.T) {
+	blockSizes := map[string]int{"DES": 8, "Rijndael": 16}
+	secrets := [][]byte{
+		[]byte("attack at dawn"),
+		[]byte("exactly sixteen!"),
+		[]byte("a longer secret message that spans several cipher blocks"),
+	}
+
+	for name, victim := range newTestVictims(t) {
+		for _, secret := range secrets {
+			ciphertext, err := victim.Encrypt(secret)
+			if err != nil {
+				t.Fatalf("%s: Encrypt failed: %v", name, err)
+			}
+
+			attacker := NewPaddingOracleAttacker(NewContextOracle(victim), blockSizes[name])
+			result, err := attacker.Decrypt(ciphertext)
+			if err != nil {
+				t.Fatalf("%s: attack failed: %v", name, err)
+			}
+			if !bytes.Equal(result.Plaintext, secret) {
+				t.Errorf("%s: recovered %q, want %q", name, result.Plaintext, secret)
+			}
+
+			blocks := len(ciphertext)/blockSizes[name] - 1
+			if result.Queries == 0 || result.Queries > blocks*blockSizes[name]*257 {
+				t.Errorf("%s: unexpected query count %d for %d blocks", name, result.Queries, blocks)
+			}
+			if attacker.Queries() != result.Queries {
+				t.Errorf("%s: Queries() = %d, want %d", name, attacker.Queries(), result.Queries)
+			}
+		}
+	}
+}
+
+func TestPaddingOracleForgesCiphertext(t *testing.T) {
+	blockSizes := map[string]int{"DES": 8, "Rijndael": 16}
+	chosen := []byte(`{"user":"mallory","admin":true}`)
+
+	for name, victim := range newTestVictims(t) {
+		attacker := NewPaddingOracleAttacker(NewContextOracle(victim), blockSizes[name])
+		result, err := attacker.Forge(chosen)
+		if err != nil {
+			t.Fatalf("%s: Forge failed: %v", name, err)
+		}
+		if result.Queries == 0 {
+			t.Errorf("%s: Forge reported no oracle queries", name)
+		}
+
+		decrypted, err := victim.Decrypt(result.Ciphertext)
+		if err != nil {
+			t.Fatalf("%s: victim rejected forged ciphertext: %v", name, err)
+		}
+		if !bytes.Equal(decrypted, chosen) {
+			t.Errorf("%s: forged ciphertext decrypts to %q, want %q", name, decrypted, chosen)
+		}
+	}
+}
+
+func TestPaddingOracleErrors(t *testing.T) {
+	attacker := NewPaddingOracleAttacker(func([]byte) bool { return false }, 8)
+	if _, err := attacker.Decrypt(make([]byte, 12)); err == nil {
+		t.Error("Expected error for ciphertext not aligned to blocks")
+	}
+	if _, err := attacker.Decrypt(make([]byte, 8)); err == nil {
+		t.Error("Expected error for ciphertext without blocks after IV")
+	}
+	if _, err := attacker.Decrypt(make([]byte, 16)); err == nil {
+		t.Error("Expected error when oracle never accepts padding")
+	}
+	if attacker.Queries() != 256 {
+		t.Errorf("Queries() = %d, want 256 for one exhausted byte", attacker.Queries())
+	}
+}
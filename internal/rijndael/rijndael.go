@@ -3,6 +3,8 @@ package rijndael
 import (
 	"fmt"
 	"sync"
+
+	"github.com/Qwental/crypota/internal/interfaces"
 )
 
 type RijndaelCipher struct {
@@ -169,3 +171,7 @@ func addRoundKey(state [][]byte, roundKey []byte) [][]byte {
 	}
 	return result
 }
+
+func (r *RijndaelCipher) Identify() (interfaces.CipherID, byte) {
+	return interfaces.CipherRijndael, r.modPoly
+}
@@ -172,6 +172,7 @@ func TestRijndaelComprehensive(t *testing.T) {
 		{modes.CFB, "CFB"},
 		{modes.OFB, "OFB"},
 		{modes.CTR, "CTR"},
+		{modes.RandomDelta, "RandomDelta"},
 	}
 
 	plaintext := []byte("This is a comprehensive test for Rijndael encryption with various configurations.")
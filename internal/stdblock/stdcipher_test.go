@@ -0,0 +1,172 @@
+package stdblock
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/rand"
+	"testing"
+
+	"github.com/Qwental/crypota/internal/deal"
+	crypotades "github.com/Qwental/crypota/internal/des"
+	"github.com/Qwental/crypota/internal/feistel"
+	"github.com/Qwental/crypota/internal/interfaces"
+	"github.com/Qwental/crypota/internal/modes"
+	"github.com/Qwental/crypota/internal/rijndael"
+)
+
+// cipher.NewGCM поверх Rijndael должен совпадать с modes.GCMMode на том же шифре
+func TestBlockGCMMatchesModes(t *testing.T) {
+	key := make([]byte, 16)
+	nonce := make([]byte, 12)
+	plaintext := make([]byte, 100)
+	rand.Read(key)
+	rand.Read(nonce)
+	rand.Read(plaintext)
+
+	rijndaelCipher, err := rijndael.NewRijndaelCipher(16, 16, 0x1B)
+	if err != nil {
+		t.Fatalf("NewRijndaelCipher failed: %v", err)
+	}
+	if err := rijndaelCipher.SetKey(key); err != nil {
+		t.Fatalf("SetKey failed: %v", err)
+	}
+	gcm, err := cipher.NewGCM(NewBlock(rijndaelCipher))
+	if err != nil {
+		t.Fatalf("cipher.NewGCM failed: %v", err)
+	}
+
+	ciphertext := gcm.Seal(nil, nonce, plaintext, []byte("header"))
+	expected, err := modes.NewGCMMode(nonce, []byte("header")).Encrypt(rijndaelCipher, plaintext)
+	if err != nil {
+		t.Fatalf("modes GCM failed: %v", err)
+	}
+	if !bytes.Equal(ciphertext, expected) {
+		t.Fatalf("cipher.NewGCM over Rijndael differs from modes.GCMMode")
+	}
+	if _, err := gcm.Open(nil, nonce, ciphertext, []byte("other")); err == nil {
+		t.Error("Expected authentication failure for wrong associated data")
+	}
+}
+
+func TestBlockCBCMatchesStandardLibrary(t *testing.T) {
+	key := []byte{0x13, 0x34, 0x57, 0x79, 0x9B, 0xBC, 0xDF, 0xF1}
+	iv := make([]byte, 8)
+	plaintext := make([]byte, 64)
+	rand.Read(iv)
+	rand.Read(plaintext)
+
+	desCipher := crypotades.NewDESCipher()
+	if err := desCipher.SetKey(key); err != nil {
+		t.Fatalf("SetKey failed: %v", err)
+	}
+	stdBlock, _ := des.NewCipher(key)
+
+	ciphertext := make([]byte, len(plaintext))
+	cipher.NewCBCEncrypter(NewBlock(desCipher), iv).CryptBlocks(ciphertext, plaintext)
+	expected := make([]byte, len(plaintext))
+	cipher.NewCBCEncrypter(stdBlock, iv).CryptBlocks(expected, plaintext)
+	if !bytes.Equal(ciphertext, expected) {
+		t.Fatalf("CBC over DES differs from crypto/des")
+	}
+
+	// расшифрование на месте: dst и src совпадают
+	cipher.NewCBCDecrypter(NewBlock(desCipher), iv).CryptBlocks(ciphertext, ciphertext)
+	if !bytes.Equal(ciphertext, plaintext) {
+		t.Errorf("In-place CBC decryption failed")
+	}
+}
+
+func TestBlockRoundTripOtherCiphers(t *testing.T) {
+	dealCipher, err := deal.NewDEALCipher(16)
+	if err != nil {
+		t.Fatalf("NewDEALCipher failed: %v", err)
+	}
+	if err := dealCipher.SetKey([]byte("0123456789abcdef")); err != nil {
+		t.Fatalf("DEAL SetKey failed: %v", err)
+	}
+
+	feistelCipher := feistel.NewFeistelCipher(crypotades.NewDESKeyScheduler(), crypotades.NewDESRoundFunction(), 16, 8)
+	if err := feistelCipher.SetKey([]byte{0x13, 0x34, 0x57, 0x79, 0x9B, 0xBC, 0xDF, 0xF1}); err != nil {
+		t.Fatalf("Feistel SetKey failed: %v", err)
+	}
+
+	for name, c := range map[string]interfaces.BlockCipher{"DEAL": dealCipher, "Feistel": feistelCipher} {
+		block := NewBlock(c)
+		iv := make([]byte, block.BlockSize())
+		plaintext := make([]byte, 10*block.BlockSize())
+		rand.Read(iv)
+		rand.Read(plaintext)
+
+		ciphertext := make([]byte, len(plaintext))
+		cipher.NewCTR(block, iv).XORKeyStream(ciphertext, plaintext)
+		expected, err := modes.NewCTRMode(iv).Encrypt(c, plaintext)
+		if err != nil {
+			t.Fatalf("%s: modes CTR failed: %v", name, err)
+		}
+		if !bytes.Equal(ciphertext, expected) {
+			t.Errorf("%s: cipher.NewCTR differs from modes.CTRMode", name)
+		}
+
+		decrypted := make([]byte, len(plaintext))
+		cipher.NewCBCEncrypter(block, iv).CryptBlocks(ciphertext, plaintext)
+		cipher.NewCBCDecrypter(block, iv).CryptBlocks(decrypted, ciphertext)
+		if !bytes.Equal(decrypted, plaintext) {
+			t.Errorf("%s: CBC round-trip failed", name)
+		}
+	}
+}
+
+func TestBlockPanicsOnShortInput(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected panic for short input")
+		}
+	}()
+	NewBlock(crypotades.NewDESCipher()).Encrypt(make([]byte, 8), make([]byte, 4))
+}
+
+func TestBlockCipherMatchesStandardModes(t *testing.T) {
+	key := make([]byte, 32)
+	iv := make([]byte, 16)
+	plaintext := make([]byte, 80)
+	rand.Read(key)
+	rand.Read(iv)
+	rand.Read(plaintext)
+
+	c, err := NewBlockCipherFunc(aes.NewCipher, key)
+	if err != nil {
+		t.Fatalf("NewBlockCipherFunc failed: %v", err)
+	}
+	stdBlock, _ := aes.NewCipher(key)
+
+	ciphertext, err := modes.NewCBCMode(iv).Encrypt(c, plaintext)
+	if err != nil {
+		t.Fatalf("CBC Encrypt failed: %v", err)
+	}
+	expected := make([]byte, len(plaintext))
+	cipher.NewCBCEncrypter(stdBlock, iv).CryptBlocks(expected, plaintext)
+	if !bytes.Equal(ciphertext, expected) {
+		t.Errorf("modes.CBCMode differs from cipher.NewCBCEncrypter")
+	}
+
+	ciphertext, err = modes.NewOFBMode(iv).Encrypt(c, plaintext)
+	if err != nil {
+		t.Fatalf("OFB Encrypt failed: %v", err)
+	}
+	cipher.NewOFB(stdBlock, iv).XORKeyStream(expected, plaintext)
+	if !bytes.Equal(ciphertext, expected) {
+		t.Errorf("modes.OFBMode differs from cipher.NewOFB")
+	}
+
+	if err := c.SetKey(make([]byte, 5)); err == nil {
+		t.Error("Expected error for invalid AES key size")
+	}
+	if _, err := c.EncryptBlock(make([]byte, 8)); err == nil {
+		t.Error("Expected error for wrong block length")
+	}
+	if err := NewBlockCipher(stdBlock).SetKey(key); err == nil {
+		t.Error("Expected error when changing key of a fixed block")
+	}
+}